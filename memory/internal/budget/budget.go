@@ -0,0 +1,108 @@
+// Package budget tracks per-session token usage against soft/hard limits
+// and fires a priority-ordered chain of BudgetActions when a limit is
+// crossed, modeled on TiDB's session-level memory quota tracker: actions
+// run cheapest-first and the tracker falls through to the next one
+// whenever an action can't free enough headroom on its own.
+package budget
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrBudgetExceeded is returned by BudgetTracker.Record when usage is at or
+// above HardLimit and the registered action chain couldn't free enough
+// headroom to bring it back under.
+type ErrBudgetExceeded struct {
+	SessionID int
+	Used      int64
+	HardLimit int64
+}
+
+func (e *ErrBudgetExceeded) Error() string {
+	return fmt.Sprintf("session %d: token budget exceeded (%d/%d used)", e.SessionID, e.Used, e.HardLimit)
+}
+
+// BudgetAction is one step in the chain a BudgetTracker fires when usage
+// crosses a threshold. Actions run in ascending Priority order (cheapest/
+// least destructive first); a BudgetTracker moves on to the next action
+// whenever one returns freed == 0.
+type BudgetAction interface {
+	Name() string
+	Priority() int
+	Action(sessionID int, used int64) (freed int64, err error)
+}
+
+// BudgetTracker accumulates estimated token usage for one session across
+// calls to Record and fires its registered actions when usage crosses
+// SoftLimit or HardLimit. Callers reconstruct a BudgetTracker from the
+// persisted session_budgets row on each invocation (see
+// commands.CmdAddTokenMetrics) rather than holding one in memory, since the
+// CLI process doesn't stay alive between commands.
+type BudgetTracker struct {
+	SessionID    int
+	SoftLimit    int64
+	HardLimit    int64
+	Used         int64
+	PeakUsage    int64
+	ActionsFired int64
+
+	actions []BudgetAction
+}
+
+// NewBudgetTracker builds a tracker seeded from a session's persisted
+// watermarks (used/peak/actionsFired), so Register+Record continue from
+// where the last invocation left off.
+func NewBudgetTracker(sessionID int, softLimit, hardLimit, used, peakUsage, actionsFired int64) *BudgetTracker {
+	return &BudgetTracker{
+		SessionID:    sessionID,
+		SoftLimit:    softLimit,
+		HardLimit:    hardLimit,
+		Used:         used,
+		PeakUsage:    peakUsage,
+		ActionsFired: actionsFired,
+	}
+}
+
+// Register adds action to the chain, keeping it sorted by ascending
+// Priority.
+func (t *BudgetTracker) Register(action BudgetAction) {
+	t.actions = append(t.actions, action)
+	sort.SliceStable(t.actions, func(i, j int) bool {
+		return t.actions[i].Priority() < t.actions[j].Priority()
+	})
+}
+
+// Record adds delta tokens to the running total, updates PeakUsage, and —
+// once Used reaches SoftLimit — walks the action chain in priority order
+// until Used drops back under HardLimit or the chain is exhausted. An
+// action that errors or frees nothing is skipped in favor of the next one.
+// Record only returns ErrBudgetExceeded when Used is still at or above
+// HardLimit after every action has had a turn.
+func (t *BudgetTracker) Record(delta int64) error {
+	t.Used += delta
+	if t.Used > t.PeakUsage {
+		t.PeakUsage = t.Used
+	}
+	if t.Used < t.SoftLimit {
+		return nil
+	}
+	for _, action := range t.actions {
+		freed, err := action.Action(t.SessionID, t.Used)
+		if err != nil || freed <= 0 {
+			continue
+		}
+		t.Used -= freed
+		if t.Used < 0 {
+			t.Used = 0
+		}
+		t.ActionsFired++
+		if t.Used < t.HardLimit {
+			return nil
+		}
+	}
+	if t.Used >= t.HardLimit {
+		return &ErrBudgetExceeded{SessionID: t.SessionID, Used: t.Used, HardLimit: t.HardLimit}
+	}
+	return nil
+}
@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,21 +13,49 @@ import (
 	"time"
 
 	"claude-turbo-search/memorydb/internal/db"
+	"claude-turbo-search/memorydb/internal/embeddings"
 	"claude-turbo-search/memorydb/internal/entity"
+	"claude-turbo-search/memorydb/internal/metrics"
+	"claude-turbo-search/memorydb/internal/migrations"
+	"claude-turbo-search/memorydb/internal/models"
+	"claude-turbo-search/memorydb/internal/report"
+	"claude-turbo-search/memorydb/internal/store"
+	"claude-turbo-search/memorydb/internal/tokenizer"
 	"claude-turbo-search/memorydb/internal/vector"
 )
 
 type App struct {
-	RepoRoot         string
-	MemoryDir        string
-	DBFile           string
-	ScriptDir        string
-	SchemaFile       string
-	MetadataSchema   string
+	RepoRoot  string
+	MemoryDir string
+	DBFile    string
+	// ScriptDir and the Schema fields below are no longer read at startup —
+	// schema now lives in internal/migrations as embedded SQL — but are
+	// kept so embeddings.sh can still be located under the same directory.
+	ScriptDir          string
+	SchemaFile         string
+	MetadataSchema     string
 	VectorSchema       string
 	TokenMetricsSchema string
 	EmbeddingsScript   string
 	DB                 *db.Client
+	// Store backs the stats, token-metrics and budget commands (CmdStats,
+	// CmdTokenStats, CmdAddTokenMetrics, CmdTokenDrift, CmdSetBudget and the
+	// budget actions) through the MemoryStore interface, so a build tag
+	// (sqlite/badger/postgres) can swap the engine underneath without
+	// touching those commands. Other commands still use DB directly; see
+	// internal/store's package doc.
+	Store store.MemoryStore
+	// Metrics collects counters/histograms for every DB.Run/RunSQL/Exec
+	// call, attached to DB below so instrumentation lives centrally in
+	// internal/db rather than in each Cmd*. CmdServeMetrics renders it.
+	Metrics *metrics.Registry
+	// Format is the --format flag value ("", "text", "json", "ndjson", or
+	// "prom"), set by Execute before dispatch. Each reporter picks its own
+	// default when Format is "", matching its pre-existing behavior.
+	Format string
+	// embedder is the lazily-built, cached embeddings.Provider backing
+	// generateEmbedding/drainEmbeddingQueue. See embeddingProvider.
+	embedder embeddings.Provider
 }
 
 type scoredResult struct {
@@ -41,21 +67,49 @@ type scoredResult struct {
 
 func New(repoRoot, scriptDir string) *App {
 	dbFile := filepath.Join(repoRoot, ".claude-memory", "memory.db")
+	dbClient := db.New(dbFile)
+	registry := metrics.NewRegistry()
+	dbClient.Metrics = registry
 	return &App{
-		RepoRoot:         repoRoot,
-		MemoryDir:        filepath.Join(repoRoot, ".claude-memory"),
-		DBFile:           dbFile,
-		ScriptDir:        scriptDir,
+		RepoRoot:           repoRoot,
+		MemoryDir:          filepath.Join(repoRoot, ".claude-memory"),
+		DBFile:             dbFile,
+		ScriptDir:          scriptDir,
 		SchemaFile:         filepath.Join(scriptDir, "schema.sql"),
 		MetadataSchema:     filepath.Join(scriptDir, "schema-metadata.sql"),
 		VectorSchema:       filepath.Join(scriptDir, "schema-vector.sql"),
 		TokenMetricsSchema: filepath.Join(scriptDir, "schema-token-metrics.sql"),
-		EmbeddingsScript: filepath.Join(scriptDir, "embeddings.sh"),
-		DB:               db.New(dbFile),
+		EmbeddingsScript:   filepath.Join(scriptDir, "embeddings.sh"),
+		DB:                 dbClient,
+		Store:              newStore(dbFile),
+		Metrics:            registry,
 	}
 }
 
+// newStore opens the MemoryStore for dbFile via whichever backend's New
+// (sqlite/badger/postgres) is compiled in. Building an unconfigured badger
+// or postgres backend can't fail at construction time — both are lazy, like
+// internal/db.Client — so this panics rather than threading an error
+// through App's constructor for what should be a compile-time decision.
+func newStore(dbFile string) store.MemoryStore {
+	s, err := store.New(dbFile)
+	if err != nil {
+		panic(fmt.Sprintf("failed to construct memory store: %v", err))
+	}
+	return s
+}
+
+// Execute dispatches cmd, first stripping a "--format=text|json|ndjson|prom"
+// flag out of args (it can appear anywhere) and recording it on a.Format.
+// Only CmdStats and CmdTokenStats read it today — see internal/report —
+// but it's parsed centrally here so later reporters don't need their own
+// copy of this scan. "knowledge-graph" is excluded: its own --format= (dot/
+// graphml/json, see kgParseExportOpts) would otherwise be consumed here
+// before CmdKnowledgeGraph ever sees it.
 func (a *App) Execute(cmd string, args []string) error {
+	if cmd != "knowledge-graph" {
+		args = a.extractFormatFlag(args)
+	}
 	switch cmd {
 	case "init":
 		return a.CmdInit()
@@ -100,23 +154,69 @@ func (a *App) Execute(cmd string, args []string) error {
 		searches := parseIntOrDefault(arg(args, 1), 0)
 		filesRead := parseIntOrDefault(arg(args, 2), 0)
 		filesEdited := parseIntOrDefault(arg(args, 3), 0)
-		return a.CmdAddTokenMetrics(sessionID, searches, filesRead, filesEdited)
+		estimate := false
+		var content []string
+		rest := []string{}
+		if len(args) > 4 {
+			rest = args[4:]
+		}
+		for _, c := range rest {
+			if c == "--estimate" {
+				estimate = true
+				continue
+			}
+			content = append(content, c)
+		}
+		return a.CmdAddTokenMetrics(sessionID, searches, filesRead, filesEdited, estimate, content)
 	case "token-stats":
 		return a.CmdTokenStats()
+	case "token-drift":
+		return a.CmdTokenDrift()
+	case "set-budget":
+		sessionID := parseIntOrDefault(arg(args, 0), 0)
+		softLimit := int64(parseIntOrDefault(arg(args, 1), 0))
+		hardLimit := int64(parseIntOrDefault(arg(args, 2), 0))
+		return a.CmdSetBudget(sessionID, softLimit, hardLimit)
+	case "check-budget":
+		sessionID := parseIntOrDefault(arg(args, 0), 0)
+		return a.CmdCheckBudget(sessionID)
+	case "serve-metrics":
+		addr := arg(args, 0)
+		if addr == "" {
+			addr = ":9090"
+		}
+		return a.CmdServeMetrics(addr)
 	case "knowledge-graph":
 		view := arg(args, 0)
 		if view == "" {
 			view = "full"
 		}
 		entity := arg(args, 1)
-		return a.CmdKnowledgeGraph(view, entity)
+		if view == "query" {
+			entity = strings.TrimPrefix(strings.Join(args[1:], " "), "--query ")
+			return a.CmdKnowledgeGraph(view, entity)
+		}
+		if view == "export" || view == "gc" {
+			return a.CmdKnowledgeGraph(view, "", args[1:]...)
+		}
+		extra := []string{}
+		if len(args) > 2 {
+			extra = args[2:]
+		}
+		return a.CmdKnowledgeGraph(view, entity, extra...)
+	case "migrate":
+		return a.CmdMigrate(args)
+	case "reindex":
+		return a.CmdReindex()
+	case "migrate-vectors":
+		return a.CmdMigrateVectors()
 	default:
 		return errors.New("unknown command")
 	}
 }
 
 func Usage() string {
-	return "Usage: memorydb {init|init-vector|init-metadata|init-token-metrics|search|vsearch|add-session|add-knowledge|add-fact|add-token-metrics|recent|context|embed|consolidate|entity-search|stats|token-stats|knowledge-graph}"
+	return "Usage: memorydb [--format=text|json|ndjson|prom] {init|init-vector|init-metadata|init-token-metrics|migrate [up|down|status|to <n>]|search|vsearch|reindex|migrate-vectors|add-session|add-knowledge|add-fact|add-token-metrics <session_id> <searches> <files_read> <files_edited> [--estimate] [content_or_path...]|recent|context|embed|consolidate|entity-search|stats|token-stats|token-drift|set-budget <session_id> <soft_limit> <hard_limit>|check-budget <session_id>|serve-metrics [addr]|knowledge-graph [full|stats|graph|timeline|explore|query|export|path|centrality|gc] [entity [--depth N]|a b|--query \"...\"|--format=dot --output=file|--dry-run --vacuum --min-age=30 days --gc-lease=1h]}"
 }
 
 func arg(args []string, idx int) string {
@@ -134,6 +234,22 @@ func parseIntOrDefault(s string, def int) int {
 	return n
 }
 
+// extractFormatFlag scans args for a "--format=..." entry (it can appear
+// anywhere, matching kg_gc.go's "--dry-run"/"--min-age="-style flag
+// scanning), records its value on a.Format, and returns args with that
+// entry removed.
+func (a *App) extractFormatFlag(args []string) []string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--format=") {
+			a.Format = strings.TrimPrefix(arg, "--format=")
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
 func (a *App) ensureDir() error {
 	return os.MkdirAll(a.MemoryDir, 0o755)
 }
@@ -143,6 +259,21 @@ func (a *App) dbExists() bool {
 	return err == nil
 }
 
+// EnsureSchema applies every pending migration. It replaces the old
+// init/init-metadata/init-vector/init-token-metrics dance: callers no
+// longer need to know which ad-hoc schema file to load or guess whether
+// it's already been applied via HasTable checks.
+func (a *App) EnsureSchema() error {
+	if err := a.ensureDir(); err != nil {
+		return err
+	}
+	conn, err := a.DB.DB()
+	if err != nil {
+		return err
+	}
+	return migrations.New(conn).EnsureLatest()
+}
+
 func normalizeDateTokens(text string) string {
 	today := time.Now().Format("2006-01-02")
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
@@ -226,9 +357,9 @@ func (a *App) extractEntities(sourceType string, sourceID int, text, filesJSON s
 		if val == "" {
 			return
 		}
-		sql := fmt.Sprintf(`INSERT OR IGNORE INTO entity_metadata (entity, entity_type, source_type, source_id)
-VALUES ('%s', '%s', '%s', %d);`, db.SQLQuote(val), db.SQLQuote(entityType), db.SQLQuote(sourceType), sourceID)
-		_, _ = a.DB.RunSQL(sql)
+		_, _ = a.DB.Exec(
+			`INSERT OR IGNORE INTO entity_metadata (entity, entity_type, source_type, source_id) VALUES (?, ?, ?, ?);`,
+			val, entityType, sourceType, sourceID)
 	}
 
 	files, concepts, packages := entity.Extract(text, filesJSON)
@@ -243,19 +374,12 @@ VALUES ('%s', '%s', '%s', %d);`, db.SQLQuote(val), db.SQLQuote(entityType), db.S
 	}
 }
 
+// CmdInit, CmdInitMetadata, CmdInitVector and CmdInitTokenMetrics are kept
+// as CLI-compatible entry points; all four now just ensure every pending
+// migration is applied rather than each loading its own schema file and
+// guessing via HasTable whether it already ran.
 func (a *App) CmdInit() error {
-	if err := a.ensureDir(); err != nil {
-		return err
-	}
-	if a.dbExists() {
-		fmt.Printf("Memory database already exists at %s\n", a.DBFile)
-		return nil
-	}
-	schema, err := os.ReadFile(a.SchemaFile)
-	if err != nil {
-		return fmt.Errorf("failed to read schema: %w", err)
-	}
-	if _, err := a.DB.RunSQL(string(schema)); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
 	fmt.Printf("Memory database initialized at %s\n", a.DBFile)
@@ -263,19 +387,7 @@ func (a *App) CmdInit() error {
 }
 
 func (a *App) CmdInitMetadata() error {
-	if err := a.ensureDir(); err != nil {
-		return err
-	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
-			return err
-		}
-	}
-	meta, err := os.ReadFile(a.MetadataSchema)
-	if err != nil {
-		return fmt.Errorf("failed to read metadata schema: %w", err)
-	}
-	if _, err := a.DB.RunSQL(string(meta)); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
 	fmt.Println("Metadata schema initialized.")
@@ -283,73 +395,9 @@ func (a *App) CmdInitMetadata() error {
 }
 
 func (a *App) CmdInitVector() error {
-	if err := a.ensureDir(); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
-			return err
-		}
-	}
-	if a.DB.HasTable("vector_meta") {
-		fmt.Println("Vector search already initialized.")
-		return nil
-	}
-
-	schema, err := os.ReadFile(a.VectorSchema)
-	if err == nil {
-		filtered := make([]string, 0)
-		scanner := bufio.NewScanner(bytes.NewReader(schema))
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.Contains(line, "load_extension") {
-				continue
-			}
-			filtered = append(filtered, line)
-		}
-		if scanner.Err() == nil {
-			if _, err := a.DB.RunSQL(strings.Join(filtered, "\n")); err == nil {
-				fmt.Println("Vector search initialized.")
-				fmt.Println()
-				fmt.Println("Next steps:")
-				fmt.Printf("  1. Run embedding setup: %s setup\n", a.EmbeddingsScript)
-				fmt.Println("  2. Process existing data: memory-db.sh embed")
-				return nil
-			}
-		}
-	}
-
-	_, _ = a.DB.RunSQL("ALTER TABLE sessions ADD COLUMN embedding BLOB;")
-	_, _ = a.DB.RunSQL("ALTER TABLE knowledge ADD COLUMN embedding BLOB;")
-	_, _ = a.DB.RunSQL("ALTER TABLE facts ADD COLUMN embedding BLOB;")
-	fallback := `
-CREATE TABLE IF NOT EXISTS vector_meta (
-    key TEXT PRIMARY KEY,
-    value TEXT,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-INSERT OR REPLACE INTO vector_meta (key, value) VALUES
-    ('provider', 'ollama'),
-    ('model', 'bge-small-en'),
-    ('dimension', '384'),
-    ('version', '1');
-
-CREATE TABLE IF NOT EXISTS embedding_queue (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    source_type TEXT NOT NULL,
-    source_id INTEGER NOT NULL,
-    content TEXT NOT NULL,
-    status TEXT DEFAULT 'pending',
-    error_message TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    processed_at TIMESTAMP,
-    UNIQUE(source_type, source_id)
-);
-CREATE INDEX IF NOT EXISTS idx_embed_queue_status ON embedding_queue(status, created_at);`
-	if _, err := a.DB.RunSQL(fallback); err != nil {
-		return err
-	}
-
 	fmt.Println("Vector search initialized.")
 	fmt.Println()
 	fmt.Println("Next steps:")
@@ -363,17 +411,13 @@ func (a *App) CmdAddSession(summary, files, tools, topics string) error {
 	if summary == "" {
 		return errors.New("summary is required")
 	}
-	if err := a.ensureDir(); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
-			return err
-		}
-	}
-	sql := fmt.Sprintf(`INSERT INTO sessions (summary, files_touched, tools_used, topics)
-VALUES ('%s', '%s', '%s', '%s');`, db.SQLQuote(summary), db.SQLQuote(files), db.SQLQuote(tools), db.SQLQuote(topics))
-	if _, err := a.DB.RunSQL(sql); err != nil {
+	sf := encodeField(summary)
+	_, err := a.DB.Exec(`INSERT INTO sessions (summary, summary_encoding, summary_compressed, files_touched, tools_used, topics)
+VALUES (?, ?, ?, ?, ?, ?);`, sf.plain, sf.encoding, sf.compressed, files, tools, topics)
+	if err != nil {
 		return err
 	}
 	if id, err := a.DB.ScalarInt("SELECT MAX(id) FROM sessions;"); err == nil {
@@ -390,21 +434,22 @@ func (a *App) CmdAddKnowledge(area, summary, patterns string) error {
 	if area == "" || summary == "" {
 		return errors.New("area and summary are required")
 	}
-	if err := a.ensureDir(); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
-			return err
-		}
-	}
-	sql := fmt.Sprintf(`INSERT INTO knowledge (area, summary, patterns)
-VALUES ('%s', '%s', '%s')
+	sf := encodeField(summary)
+	pf := encodeField(patterns)
+	_, err := a.DB.Exec(`INSERT INTO knowledge (area, summary, summary_encoding, summary_compressed, patterns, patterns_encoding, patterns_compressed)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(area) DO UPDATE SET
   summary = excluded.summary,
+  summary_encoding = excluded.summary_encoding,
+  summary_compressed = excluded.summary_compressed,
   patterns = excluded.patterns,
-  updated_at = CURRENT_TIMESTAMP;`, db.SQLQuote(area), db.SQLQuote(summary), db.SQLQuote(patterns))
-	if _, err := a.DB.RunSQL(sql); err != nil {
+  patterns_encoding = excluded.patterns_encoding,
+  patterns_compressed = excluded.patterns_compressed,
+  updated_at = CURRENT_TIMESTAMP;`, area, sf.plain, sf.encoding, sf.compressed, pf.plain, pf.encoding, pf.compressed)
+	if err != nil {
 		return err
 	}
 	if id, err := a.DB.ScalarInt(fmt.Sprintf("SELECT id FROM knowledge WHERE area = '%s';", db.SQLQuote(area))); err == nil {
@@ -423,17 +468,13 @@ func (a *App) CmdAddFact(fact, category string) error {
 	if category == "" {
 		category = "general"
 	}
-	if err := a.ensureDir(); err != nil {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
-			return err
-		}
-	}
-	sql := fmt.Sprintf(`INSERT INTO facts (fact, category)
-VALUES ('%s', '%s');`, db.SQLQuote(fact), db.SQLQuote(category))
-	if _, err := a.DB.RunSQL(sql); err != nil {
+	ff := encodeField(fact)
+	_, err := a.DB.Exec(`INSERT INTO facts (fact, fact_encoding, fact_compressed, category)
+VALUES (?, ?, ?, ?);`, ff.plain, ff.encoding, ff.compressed, category)
+	if err != nil {
 		return err
 	}
 	if id, err := a.DB.ScalarInt("SELECT MAX(id) FROM facts;"); err == nil {
@@ -458,17 +499,20 @@ func (a *App) CmdSearch(query string, limit int) error {
 	if strings.TrimSpace(query) == "" {
 		return errors.New("query is required")
 	}
-	sql := fmt.Sprintf(`SELECT source_type, source_id,
+	rows, err := db.Scan[models.SearchRow](a.DB, `SELECT source_type, source_id,
   snippet(memory_fts, 0, '**', '**', '...', 32) as match
 FROM memory_fts
-WHERE memory_fts MATCH '%s'
+WHERE memory_fts MATCH ?
 ORDER BY rank
-LIMIT %d;`, db.SQLQuote(query), limit)
-	out, err := a.DB.Run("-json", sql)
+LIMIT ?;`, query, limit)
 	if err != nil {
 		return err
 	}
-	fmt.Print(out)
+	out, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
 	return nil
 }
 
@@ -481,8 +525,8 @@ func (a *App) CmdVSearch(query string, limit int) error {
 		fmt.Println("Falling back to FTS search...")
 		return a.CmdSearch(query, limit)
 	}
-	if _, err := os.Stat(a.EmbeddingsScript); err != nil {
-		fmt.Println("Embeddings script not found. Falling back to FTS search...")
+	if _, err := a.embeddingProvider(); err != nil {
+		fmt.Println("No embedding provider available. Falling back to FTS search...")
 		return a.CmdSearch(query, limit)
 	}
 
@@ -492,131 +536,363 @@ func (a *App) CmdVSearch(query string, limit int) error {
 		return a.CmdSearch(query, limit)
 	}
 
-	results := make([]scoredResult, 0)
+	var results []scoredResult
+	if idx, ok := a.loadVectorIndex(); ok {
+		if _, err := a.syncVectorIndex(idx); err != nil {
+			fmt.Println("Warning: failed to sync vector index:", err)
+		}
+		results = a.vsearchViaIndex(idx, queryVec, limit)
+	}
+	if len(results) == 0 {
+		results = a.bruteForceVSearch(queryVec)
+	}
 
-	sessionRows, _ := a.DB.Run("-separator", "\t", "SELECT id, summary, hex(embedding) FROM sessions WHERE embedding IS NOT NULL;")
-	for _, line := range strings.Split(strings.TrimSpace(sessionRows), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
+	sort.Slice(results, func(i, j int) bool { return results[i].similarity > results[j].similarity })
+	printed := 0
+	for _, r := range results {
+		if printed >= limit {
+			break
 		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) != 3 {
+		if r.similarity <= 0.3 {
 			continue
 		}
-		id, err := strconv.Atoi(parts[0])
+		fmt.Printf("[%s:%d] (sim: %.3f) %s\n", r.sourceType, r.sourceID, r.similarity, truncate(r.content, 100))
+		printed++
+	}
+	return nil
+}
+
+// bruteForceVSearch is the original linear cosine-similarity scan over
+// every embedded session/knowledge/fact row. CmdVSearch falls back to it
+// when vector_index doesn't exist yet (old DBs) or is still empty.
+//
+// It scores against the quantized embedding form directly (see
+// vector.DecodeEmbeddingHex/CosineSimilarityQuantized) rather than
+// decoding every row to []float64, so the query vector is quantized once
+// up front and the per-row cost stays an int8 dot product.
+// bruteForceVSearch scans every embedded row across sessions/knowledge/facts
+// and scores them against queryVec. It scans rows through db.Scan rather
+// than db.Client.Run so a summary or fact containing a tab can't shift the
+// rest of that row's fields the way splitting on "\t" would.
+func (a *App) bruteForceVSearch(queryVec []float64) []scoredResult {
+	queryQ := vector.Quantize(queryVec)
+	results := make([]scoredResult, 0)
+
+	sessionRows, _ := db.Scan[models.EmbeddedSessionRow](a.DB, `SELECT id, summary, summary_encoding,
+    hex(summary_compressed) AS summary_compressed_hex, hex(embedding) AS embedding_hex
+FROM sessions WHERE embedding IS NOT NULL;`)
+	for _, r := range sessionRows {
+		content, err := decodeField(r.Summary, r.SummaryEncoding, r.SummaryCompressed)
 		if err != nil {
 			continue
 		}
-		emb, err := vector.HexBlobToFloat64(parts[2])
+		rowQ, err := vector.DecodeEmbeddingHex(r.Embedding)
 		if err != nil {
 			continue
 		}
-		results = append(results, scoredResult{sourceType: "session", sourceID: id, content: parts[1], similarity: vector.CosineSimilarity(queryVec, emb)})
+		results = append(results, scoredResult{sourceType: "session", sourceID: r.ID, content: content, similarity: vector.CosineSimilarityQuantized(queryQ, rowQ)})
 	}
 
-	knowledgeRows, _ := a.DB.Run("-separator", "\t", "SELECT id, area, summary, hex(embedding) FROM knowledge WHERE embedding IS NOT NULL;")
-	for _, line := range strings.Split(strings.TrimSpace(knowledgeRows), "\n") {
-		if strings.TrimSpace(line) == "" {
+	knowledgeRows, _ := db.Scan[models.KnowledgeRow](a.DB, `SELECT id, area, summary, summary_encoding,
+    hex(summary_compressed) AS summary_compressed_hex, hex(embedding) AS embedding_hex
+FROM knowledge WHERE embedding IS NOT NULL;`)
+	for _, r := range knowledgeRows {
+		summary, err := decodeField(r.Summary, r.SummaryEncoding, r.SummaryCompressed)
+		if err != nil {
 			continue
 		}
-		parts := strings.SplitN(line, "\t", 4)
-		if len(parts) != 4 {
+		rowQ, err := vector.DecodeEmbeddingHex(r.Embedding)
+		if err != nil {
 			continue
 		}
-		id, err := strconv.Atoi(parts[0])
+		content := fmt.Sprintf("%s: %s", r.Area, summary)
+		results = append(results, scoredResult{sourceType: "knowledge", sourceID: r.ID, content: content, similarity: vector.CosineSimilarityQuantized(queryQ, rowQ)})
+	}
+
+	factRows, _ := db.Scan[models.FactRow](a.DB, `SELECT id, fact, fact_encoding,
+    hex(fact_compressed) AS fact_compressed_hex, hex(embedding) AS embedding_hex
+FROM facts WHERE embedding IS NOT NULL;`)
+	for _, r := range factRows {
+		content, err := decodeField(r.Fact, r.Encoding, r.Compressed)
 		if err != nil {
 			continue
 		}
-		emb, err := vector.HexBlobToFloat64(parts[3])
+		rowQ, err := vector.DecodeEmbeddingHex(r.Embedding)
 		if err != nil {
 			continue
 		}
-		content := fmt.Sprintf("%s: %s", parts[1], parts[2])
-		results = append(results, scoredResult{sourceType: "knowledge", sourceID: id, content: content, similarity: vector.CosineSimilarity(queryVec, emb)})
+		results = append(results, scoredResult{sourceType: "fact", sourceID: r.ID, content: content, similarity: vector.CosineSimilarityQuantized(queryQ, rowQ)})
 	}
 
-	factRows, _ := a.DB.Run("-separator", "\t", "SELECT id, fact, hex(embedding) FROM facts WHERE embedding IS NOT NULL;")
-	for _, line := range strings.Split(strings.TrimSpace(factRows), "\n") {
+	return results
+}
+
+// generateEmbedding embeds a single piece of query text through whichever
+// provider is configured. It's used for one-off vsearch queries; bulk
+// embedding of queued rows goes through drainEmbeddingQueue instead so
+// those calls can be batched.
+func (a *App) generateEmbedding(text string) ([]float64, error) {
+	p, err := a.embeddingProvider()
+	if err != nil {
+		return nil, err
+	}
+	vecs, err := p.Embed([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) == 0 || len(vecs[0]) == 0 {
+		return nil, errors.New("embedding provider returned no vector")
+	}
+	a.recordEmbeddingProvenance(p, len(vecs[0]))
+	return vecs[0], nil
+}
+
+// embeddingProvider lazily builds and caches the configured
+// embeddings.Provider, reading <memory-dir>/embedding-config.json for the
+// backend/model/endpoint. With no config file it defaults to Ollama on
+// localhost, matching the provider's own zero-value defaults.
+func (a *App) embeddingProvider() (embeddings.Provider, error) {
+	if a.embedder != nil {
+		return a.embedder, nil
+	}
+	var cfg embeddings.Config
+	cfgPath := filepath.Join(a.MemoryDir, "embedding-config.json")
+	if data, err := os.ReadFile(cfgPath); err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", cfgPath, err)
+		}
+	}
+	p, err := embeddings.New(cfg, a.EmbeddingsScript)
+	if err != nil {
+		return nil, err
+	}
+	a.embedder = embeddings.WithRetry(p, 3, 250*time.Millisecond)
+	return a.embedder, nil
+}
+
+// recordEmbeddingProvenance stamps vector_meta with the provider/model/
+// dimension that produced the most recent embedding, warning when it
+// differs from what's already recorded — that mismatch is the signal a DB
+// now holds mixed-dimension vectors and needs re-embedding + reindexing.
+func (a *App) recordEmbeddingProvenance(p embeddings.Provider, dim int) {
+	prevProvider := a.vectorMetaValue("provider")
+	prevModel := a.vectorMetaValue("model")
+	prevDim := a.vectorMetaValue("dimension")
+	dimStr := strconv.Itoa(dim)
+	switch {
+	case prevDim != "" && prevDim != dimStr:
+		fmt.Printf("Warning: embedding dimension changed (%s -> %s); existing rows are now mixed-dimension. Re-embed and run 'memorydb reindex'.\n", prevDim, dimStr)
+	case prevProvider != "" && (prevProvider != p.Name() || prevModel != p.Model()):
+		fmt.Printf("Warning: embedding provider/model changed (%s/%s -> %s/%s).\n", prevProvider, prevModel, p.Name(), p.Model())
+	}
+	_ = a.setVectorMeta("provider", p.Name())
+	_ = a.setVectorMeta("model", p.Model())
+	_ = a.setVectorMeta("dimension", dimStr)
+}
+
+func (a *App) vectorMetaValue(key string) string {
+	out, err := a.DB.Run("-separator", "\t", fmt.Sprintf("SELECT value FROM vector_meta WHERE key = '%s';", db.SQLQuote(key)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func (a *App) setVectorMeta(key, value string) error {
+	_, err := a.DB.Exec("INSERT OR REPLACE INTO vector_meta (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP);", key, value)
+	return err
+}
+
+// drainEmbeddingQueue embeds every pending embedding_queue row through the
+// configured provider, batching up to batchSize texts per request rather
+// than one process fork per row. Rows that fail are marked 'error' with
+// the cause and left out of the count so CmdEmbed can report how many
+// actually completed; a later drain retries them.
+func (a *App) drainEmbeddingQueue(batchSize int) (int, error) {
+	p, err := a.embeddingProvider()
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := a.DB.Run("-separator", "\t", "SELECT id, source_type, source_id, content FROM embedding_queue WHERE status IN ('pending', 'error') ORDER BY id;")
+	if err != nil {
+		return 0, err
+	}
+	items := make([]queueItem, 0)
+	for _, line := range strings.Split(strings.TrimSpace(rows), "\n") {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) != 3 {
+		parts := strings.SplitN(line, "\t", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		id, err1 := strconv.Atoi(parts[0])
+		sourceID, err2 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil {
 			continue
 		}
-		id, err := strconv.Atoi(parts[0])
+		items = append(items, queueItem{id: id, sourceType: parts[1], sourceID: sourceID, content: parts[3]})
+	}
+
+	done := 0
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+		texts := make([]string, len(batch))
+		for i, it := range batch {
+			texts[i] = it.content
+		}
+		vecs, err := p.Embed(texts)
 		if err != nil {
+			for _, it := range batch {
+				a.markQueueError(it.id, err)
+			}
 			continue
 		}
-		emb, err := vector.HexBlobToFloat64(parts[2])
+		for i, it := range batch {
+			if len(vecs[i]) == 0 {
+				a.markQueueError(it.id, errors.New("embedding provider returned no vector"))
+				continue
+			}
+			if err := a.writeEmbedding(it.sourceType, it.sourceID, vecs[i]); err != nil {
+				a.markQueueError(it.id, err)
+				continue
+			}
+			a.recordEmbeddingProvenance(p, len(vecs[i]))
+			if _, err := a.DB.Exec("UPDATE embedding_queue SET status = 'done', processed_at = CURRENT_TIMESTAMP WHERE id = ?;", it.id); err != nil {
+				return done, err
+			}
+			done++
+		}
+	}
+	return done, nil
+}
+
+type queueItem struct {
+	id         int
+	sourceType string
+	sourceID   int
+	content    string
+}
+
+func (a *App) writeEmbedding(sourceType string, sourceID int, vec []float64) error {
+	table, ok := map[string]string{"session": "sessions", "knowledge": "knowledge", "fact": "facts"}[sourceType]
+	if !ok {
+		return fmt.Errorf("unknown embedding source type %q", sourceType)
+	}
+	_, err := a.DB.Exec(fmt.Sprintf("UPDATE %s SET embedding = ? WHERE id = ?;", table), vector.QuantizeVector(vec), sourceID)
+	return err
+}
+
+func (a *App) markQueueError(queueID int, cause error) {
+	_, _ = a.DB.Exec("UPDATE embedding_queue SET status = 'error', error_message = ? WHERE id = ?;", cause.Error(), queueID)
+}
+
+// queuePendingEmbeddings finds every session/knowledge/fact row without an
+// embedding yet and inserts its (decompressed) content into
+// embedding_queue. This used to be a single INSERT...SELECT per table, but
+// summary/patterns/fact may now be Snappy-compressed (see
+// text_compression.go) and SQLite has no way to decompress a column
+// in-query, so each row is read, decoded in Go, and inserted individually.
+func (a *App) queuePendingEmbeddings() error {
+	sessionRows, err := db.Scan[models.SessionPendingEmbeddingRow](a.DB,
+		"SELECT id, summary, summary_encoding, hex(summary_compressed) AS summary_compressed_hex, COALESCE(topics, '') AS topics FROM sessions WHERE embedding IS NULL;")
+	if err != nil {
+		return err
+	}
+	for _, r := range sessionRows {
+		summary, err := decodeField(r.Summary, r.SummaryEncoding, r.SummaryCompressed)
 		if err != nil {
 			continue
 		}
-		results = append(results, scoredResult{sourceType: "fact", sourceID: id, content: parts[1], similarity: vector.CosineSimilarity(queryVec, emb)})
+		if err := a.enqueueEmbedding("session", r.ID, summary+" "+r.Topics); err != nil {
+			return err
+		}
 	}
 
-	sort.Slice(results, func(i, j int) bool { return results[i].similarity > results[j].similarity })
-	printed := 0
-	for _, r := range results {
-		if printed >= limit {
-			break
+	knowledgeRows, err := db.Scan[models.KnowledgePendingEmbeddingRow](a.DB,
+		`SELECT id, area, summary, summary_encoding, hex(summary_compressed) AS summary_compressed_hex,
+    patterns, patterns_encoding, hex(patterns_compressed) AS patterns_compressed_hex
+FROM knowledge WHERE embedding IS NULL;`)
+	if err != nil {
+		return err
+	}
+	for _, r := range knowledgeRows {
+		summary, err := decodeField(r.Summary, r.SummaryEncoding, r.SummaryCompressed)
+		if err != nil {
+			continue
 		}
-		if r.similarity <= 0.3 {
+		patterns, err := decodeField(r.Patterns, r.PatternsEncoding, r.PatternsCompressed)
+		if err != nil {
 			continue
 		}
-		fmt.Printf("[%s:%d] (sim: %.3f) %s\n", r.sourceType, r.sourceID, r.similarity, truncate(r.content, 100))
-		printed++
+		if err := a.enqueueEmbedding("knowledge", r.ID, r.Area+" "+summary+" "+patterns); err != nil {
+			return err
+		}
 	}
-	return nil
-}
 
-func (a *App) generateEmbedding(text string) ([]float64, error) {
-	cmd := exec.Command(a.EmbeddingsScript, "generate", text)
-	out, err := cmd.CombinedOutput()
+	factRows, err := db.Scan[models.FactPendingEmbeddingRow](a.DB,
+		"SELECT id, fact, fact_encoding, hex(fact_compressed) AS fact_compressed_hex, COALESCE(category, '') AS category FROM facts WHERE embedding IS NULL;")
 	if err != nil {
-		return nil, err
-	}
-	trimmed := strings.TrimSpace(string(out))
-	if strings.HasPrefix(trimmed, "ERROR") || trimmed == "" {
-		return nil, errors.New(trimmed)
+		return err
 	}
-	var vec []float64
-	if err := json.Unmarshal([]byte(trimmed), &vec); err != nil {
-		return nil, err
+	for _, r := range factRows {
+		fact, err := decodeField(r.Fact, r.Encoding, r.Compressed)
+		if err != nil {
+			continue
+		}
+		if err := a.enqueueEmbedding("fact", r.ID, fact+" "+r.Category); err != nil {
+			return err
+		}
 	}
-	return vec, nil
+	return nil
+}
+
+func (a *App) enqueueEmbedding(sourceType string, sourceID int, content string) error {
+	_, err := a.DB.Exec(
+		"INSERT OR IGNORE INTO embedding_queue (source_type, source_id, content, status) VALUES (?, ?, ?, 'pending');",
+		sourceType, sourceID, content,
+	)
+	return err
 }
 
 func (a *App) CmdEmbed() error {
-	if _, err := os.Stat(a.EmbeddingsScript); err != nil {
-		return fmt.Errorf("embeddings script not found at: %s", a.EmbeddingsScript)
+	if err := a.EnsureSchema(); err != nil {
+		return err
 	}
-	cfg := filepath.Join(a.MemoryDir, "embedding-config.json")
-	if _, err := os.Stat(cfg); os.IsNotExist(err) {
-		fmt.Println("Embeddings not configured. Running setup...")
-		if err := a.runExternalWithTTY(a.EmbeddingsScript, "setup"); err != nil {
-			return err
+	cfgPath := filepath.Join(a.MemoryDir, "embedding-config.json")
+	if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
+		if _, scriptErr := os.Stat(a.EmbeddingsScript); scriptErr == nil {
+			fmt.Println("Embeddings not configured. Running setup...")
+			if err := a.runExternalWithTTY(a.EmbeddingsScript, "setup"); err != nil {
+				return err
+			}
 		}
 	}
-	if a.dbExists() {
-		fmt.Println("Queueing items without embeddings...")
-		queueSQL := `
-INSERT OR IGNORE INTO embedding_queue (source_type, source_id, content, status)
-SELECT 'session', id, summary || ' ' || COALESCE(topics, ''), 'pending'
-FROM sessions WHERE embedding IS NULL;
+	fmt.Println("Queueing items without embeddings...")
+	if err := a.queuePendingEmbeddings(); err != nil {
+		return err
+	}
 
-INSERT OR IGNORE INTO embedding_queue (source_type, source_id, content, status)
-SELECT 'knowledge', id, area || ' ' || summary || ' ' || COALESCE(patterns, ''), 'pending'
-FROM knowledge WHERE embedding IS NULL;
+	fmt.Println("Embedding queued items...")
+	n, err := a.drainEmbeddingQueue(16)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Embedded %d item(s).\n", n)
 
-INSERT OR IGNORE INTO embedding_queue (source_type, source_id, content, status)
-SELECT 'fact', id, fact || ' ' || COALESCE(category, ''), 'pending'
-FROM facts WHERE embedding IS NULL;`
-		if _, err := a.DB.RunSQL(queueSQL); err != nil {
-			return err
+	if idx, ok := a.loadVectorIndex(); ok {
+		if synced, err := a.syncVectorIndex(idx); err != nil {
+			fmt.Println("Warning: failed to sync vector index:", err)
+		} else if synced > 0 {
+			fmt.Printf("Indexed %d new embedding(s).\n", synced)
 		}
 	}
-	return a.runExternalWithTTY(a.EmbeddingsScript, "batch")
+	return nil
 }
 
 func (a *App) runExternalWithTTY(command string, args ...string) error {
@@ -643,7 +919,8 @@ func (a *App) consolidate() (int, int, error) {
 
 	merged := 0
 	removed := 0
-	sessionRows, err := a.DB.Run("-separator", "\t", "SELECT id, COALESCE(topics, ''), COALESCE(summary, '') FROM sessions ORDER BY created_at DESC;")
+	sessionRows, err := db.Scan[models.SessionRow](a.DB,
+		"SELECT id, COALESCE(topics, '') AS topics, COALESCE(summary, '') AS summary FROM sessions ORDER BY created_at DESC;")
 	if err != nil {
 		return 0, 0, err
 	}
@@ -653,19 +930,8 @@ func (a *App) consolidate() (int, int, error) {
 		summary string
 	}
 	sessions := []sessionRec{}
-	for _, line := range strings.Split(strings.TrimSpace(sessionRows), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) < 3 {
-			continue
-		}
-		id, err := strconv.Atoi(parts[0])
-		if err != nil {
-			continue
-		}
-		sessions = append(sessions, sessionRec{id: id, topics: toSet(parseCSV(parts[1])), summary: parts[2]})
+	for _, r := range sessionRows {
+		sessions = append(sessions, sessionRec{id: r.ID, topics: toSet(parseCSV(r.Topics)), summary: r.Summary})
 	}
 
 	toDelete := map[int]bool{}
@@ -701,32 +967,20 @@ func (a *App) consolidate() (int, int, error) {
 		}
 	}
 
-	pairs, _ := a.DB.Run(`SELECT f1.id, f2.id FROM facts f1
+	pairs, _ := db.Scan[models.FactPairRow](a.DB, `SELECT f1.id AS id1, f2.id AS id2 FROM facts f1
 JOIN facts f2 ON f1.id < f2.id AND f1.category = f2.category
 WHERE f1.fact = f2.fact OR INSTR(f1.fact, f2.fact) > 0 OR INSTR(f2.fact, f1.fact) > 0;`)
 	factDelete := map[int]bool{}
-	for _, line := range strings.Split(strings.TrimSpace(pairs), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		id1, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-		id2, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-		if err1 != nil || err2 != nil {
-			continue
-		}
-		len1, err1 := a.DB.ScalarInt(fmt.Sprintf("SELECT LENGTH(fact) FROM facts WHERE id=%d;", id1))
-		len2, err2 := a.DB.ScalarInt(fmt.Sprintf("SELECT LENGTH(fact) FROM facts WHERE id=%d;", id2))
+	for _, p := range pairs {
+		len1, err1 := a.DB.ScalarInt(fmt.Sprintf("SELECT LENGTH(fact) FROM facts WHERE id=%d;", p.ID1))
+		len2, err2 := a.DB.ScalarInt(fmt.Sprintf("SELECT LENGTH(fact) FROM facts WHERE id=%d;", p.ID2))
 		if err1 != nil || err2 != nil {
 			continue
 		}
 		if len1 >= len2 {
-			factDelete[id2] = true
+			factDelete[p.ID2] = true
 		} else {
-			factDelete[id1] = true
+			factDelete[p.ID1] = true
 		}
 	}
 
@@ -753,25 +1007,29 @@ func (a *App) CmdEntitySearch(query, entityType string) error {
 	if strings.TrimSpace(query) == "" {
 		return errors.New("query is required")
 	}
-	filter := ""
-	if strings.TrimSpace(entityType) != "" {
-		filter = fmt.Sprintf(" AND em.entity_type = '%s'", db.SQLQuote(entityType))
-	}
-	sql := fmt.Sprintf(`SELECT em.entity, em.entity_type, em.source_type, em.source_id,
-    CASE em.source_type
+	sqlQuery := `SELECT em.entity, em.entity_type, em.source_type, em.source_id,
+    COALESCE(CASE em.source_type
         WHEN 'session' THEN (SELECT summary FROM sessions WHERE id = em.source_id)
         WHEN 'knowledge' THEN (SELECT area || ': ' || summary FROM knowledge WHERE id = em.source_id)
         WHEN 'fact' THEN (SELECT fact FROM facts WHERE id = em.source_id)
-    END as context
+    END, '') as context
 FROM entity_metadata em
-WHERE em.entity LIKE '%%%s%%'%s
+WHERE em.entity LIKE '%' || ? || '%'`
+	args := []interface{}{query}
+	if strings.TrimSpace(entityType) != "" {
+		sqlQuery += " AND em.entity_type = ?"
+		args = append(args, entityType)
+	}
+	sqlQuery += `
 ORDER BY em.created_at DESC
-LIMIT 10;`, db.SQLQuote(query), filter)
-	out, err := a.DB.Run(sql)
+LIMIT 10;`
+	rows, err := db.Scan[models.EntityContextRow](a.DB, sqlQuery, args...)
 	if err != nil {
 		return err
 	}
-	fmt.Print(out)
+	for _, r := range rows {
+		fmt.Printf("%s|%s|%s|%d|%s\n", r.Entity, r.EntityType, r.SourceType, r.SourceID, r.Context)
+	}
 	return nil
 }
 
@@ -798,56 +1056,56 @@ func (a *App) CmdContext(query string, tokenLimit int) error {
 	charLimit := tokenLimit * 4
 	var b strings.Builder
 
-	facts, err := a.DB.Run("SELECT fact FROM facts ORDER BY created_at DESC LIMIT 5;")
-	if err == nil && strings.TrimSpace(facts) != "" {
+	facts, err := db.Scan[models.FactTextRow](a.DB, "SELECT fact FROM facts ORDER BY created_at DESC LIMIT 5;")
+	if err == nil && len(facts) > 0 {
 		b.WriteString("## Project Facts\n")
-		for _, line := range strings.Split(strings.TrimSpace(facts), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
+		for _, f := range facts {
 			b.WriteString("- ")
-			b.WriteString(line)
+			b.WriteString(f.Fact)
 			b.WriteByte('\n')
 		}
 		b.WriteByte('\n')
 	}
 
 	if strings.TrimSpace(query) != "" {
-		knowSQL := fmt.Sprintf(`SELECT area, summary FROM knowledge
-WHERE area LIKE '%%%s%%' OR summary LIKE '%%%s%%'
-LIMIT 3;`, db.SQLQuote(query), db.SQLQuote(query))
-		knowledge, err := a.DB.Run(knowSQL)
-		if err == nil && strings.TrimSpace(knowledge) != "" {
+		knowledge, err := db.Scan[models.KnowledgeAreaRow](a.DB, `SELECT area, summary FROM knowledge
+WHERE area LIKE '%' || ? || '%' OR summary LIKE '%' || ? || '%'
+LIMIT 3;`, query, query)
+		if err == nil && len(knowledge) > 0 {
 			b.WriteString("## Relevant Code Areas\n")
-			b.WriteString(strings.TrimSpace(knowledge))
+			lines := make([]string, len(knowledge))
+			for i, k := range knowledge {
+				lines[i] = k.Area + "|" + k.Summary
+			}
+			b.WriteString(strings.Join(lines, "\n"))
 			b.WriteString("\n\n")
 		}
 	}
 
-	sessions, err := a.DB.Run("SELECT summary FROM sessions ORDER BY created_at DESC LIMIT 3;")
-	if err == nil && strings.TrimSpace(sessions) != "" {
+	sessions, err := db.Scan[models.SessionSummaryRow](a.DB, "SELECT summary FROM sessions ORDER BY created_at DESC LIMIT 3;")
+	if err == nil && len(sessions) > 0 {
 		b.WriteString("## Recent Work\n")
-		for _, line := range strings.Split(strings.TrimSpace(sessions), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
+		for _, s := range sessions {
 			b.WriteString("- ")
-			b.WriteString(line)
+			b.WriteString(s.Summary)
 			b.WriteByte('\n')
 		}
 		b.WriteByte('\n')
 	}
 
 	if strings.TrimSpace(query) != "" {
-		searchSQL := fmt.Sprintf(`SELECT snippet(memory_fts, 0, '', '', '...', 32) as match
+		related, err := db.Scan[models.MatchRow](a.DB, `SELECT snippet(memory_fts, 0, '', '', '...', 32) as match
 FROM memory_fts
-WHERE memory_fts MATCH '%s'
+WHERE memory_fts MATCH ?
 ORDER BY rank
-LIMIT 5;`, db.SQLQuote(query))
-		related, err := a.DB.Run(searchSQL)
-		if err == nil && strings.TrimSpace(related) != "" {
+LIMIT 5;`, query)
+		if err == nil && len(related) > 0 {
 			b.WriteString("## Related Context\n")
-			b.WriteString(strings.TrimSpace(related))
+			matches := make([]string, len(related))
+			for i, r := range related {
+				matches[i] = r.Match
+			}
+			b.WriteString(strings.Join(matches, "\n"))
 			b.WriteByte('\n')
 		}
 	}
@@ -860,55 +1118,94 @@ LIMIT 5;`, db.SQLQuote(query))
 	return nil
 }
 
+// CmdStats builds a report.StatsReport from the same a.Store.CountRows/
+// Query calls it always has and renders it via report.Render. With no
+// --format override it defaults to "text", preserving the original
+// pipe-separated table/section output (including the "Memory Database:
+// <file>" header, which only the text case prints).
 func (a *App) CmdStats() error {
 	if !a.dbExists() {
 		fmt.Println("No memory database found.")
 		return nil
 	}
-	fmt.Printf("Memory Database: %s\n\n", a.DBFile)
-	base := `SELECT 'Sessions' as type, COUNT(*) as count FROM sessions
-UNION ALL
-SELECT 'Knowledge areas', COUNT(*) FROM knowledge
-UNION ALL
-SELECT 'Facts', COUNT(*) FROM facts;`
-	out, err := a.DB.Run(base)
+
+	rpt := report.StatsReport{DBFile: a.DBFile}
+
+	sessions, err := a.Store.CountRows("sessions", "")
 	if err != nil {
 		return err
 	}
-	fmt.Print(out)
+	knowledge, err := a.Store.CountRows("knowledge", "")
+	if err != nil {
+		return err
+	}
+	facts, err := a.Store.CountRows("facts", "")
+	if err != nil {
+		return err
+	}
+	rpt.Sessions, rpt.Knowledge, rpt.Facts = int64(sessions), int64(knowledge), int64(facts)
 
-	if a.DB.HasTable("vector_meta") {
-		fmt.Println("\nVector Search: Enabled")
-		vectorStats := `SELECT 'Embedded sessions' as type, COUNT(*) as count FROM sessions WHERE embedding IS NOT NULL
-UNION ALL
-SELECT 'Embedded knowledge', COUNT(*) FROM knowledge WHERE embedding IS NOT NULL
-UNION ALL
-SELECT 'Embedded facts', COUNT(*) FROM facts WHERE embedding IS NOT NULL
-UNION ALL
-SELECT 'Pending embeddings', COUNT(*) FROM embedding_queue WHERE status = 'pending';`
-		out, err = a.DB.Run(vectorStats)
+	if a.Store.HasTable("vector_meta") {
+		embeddedSessions, err := a.Store.CountRows("sessions", "embedding IS NOT NULL")
+		if err != nil {
+			return err
+		}
+		embeddedKnowledge, err := a.Store.CountRows("knowledge", "embedding IS NOT NULL")
 		if err != nil {
 			return err
 		}
-		fmt.Print(out)
+		embeddedFacts, err := a.Store.CountRows("facts", "embedding IS NOT NULL")
+		if err != nil {
+			return err
+		}
+		pending, err := a.Store.CountRows("embedding_queue", "status = 'pending'")
+		if err != nil {
+			return err
+		}
+		rpt.Vector = &report.VectorStats{
+			EmbeddedSessions:  int64(embeddedSessions),
+			EmbeddedKnowledge: int64(embeddedKnowledge),
+			EmbeddedFacts:     int64(embeddedFacts),
+			PendingEmbeddings: int64(pending),
+		}
 	}
-	return nil
-}
 
-func (a *App) CmdInitTokenMetrics() error {
-	if err := a.ensureDir(); err != nil {
-		return err
-	}
-	if !a.dbExists() {
-		if err := a.CmdInit(); err != nil {
+	if a.Store.HasTable("session_budgets") {
+		rows, err := a.Store.Query("session_budgets", []string{"peak_usage", "actions_fired"}, "")
+		if err != nil {
 			return err
 		}
+		var peak, actionsFired int64
+		for _, r := range rows {
+			if p := rowInt(r, "peak_usage"); p > peak {
+				peak = p
+			}
+			actionsFired += rowInt(r, "actions_fired")
+		}
+		rpt.Budget = &report.BudgetStats{
+			TrackedSessions: int64(len(rows)),
+			PeakUsage:       peak,
+			ActionsFired:    actionsFired,
+		}
+	}
+
+	format := a.Format
+	if format == "" {
+		format = "text"
 	}
-	schema, err := os.ReadFile(a.TokenMetricsSchema)
+	out, err := report.Render(format, rpt)
 	if err != nil {
-		return fmt.Errorf("failed to read token metrics schema: %w", err)
+		return err
+	}
+	if format == "text" {
+		fmt.Printf("Memory Database: %s\n\n", a.DBFile)
 	}
-	if _, err := a.DB.RunSQL(string(schema)); err != nil {
+	fmt.Print(out)
+	return nil
+}
+
+func (a *App) CmdInitTokenMetrics() error {
+	if err := a.EnsureSchema(); err != nil {
 		return err
 	}
 	fmt.Println("Token metrics schema initialized.")
@@ -916,12 +1213,19 @@ func (a *App) CmdInitTokenMetrics() error {
 }
 
 func (a *App) ensureTokenMetricsTable() {
-	if !a.DB.HasTable("token_metrics") {
-		_ = a.CmdInitTokenMetrics()
+	if !a.Store.HasTable("token_metrics") {
+		_ = a.EnsureSchema()
 	}
 }
 
-func (a *App) CmdAddTokenMetrics(sessionID, searches, filesRead, filesEdited int) error {
+// CmdAddTokenMetrics records one session's token accounting. By default it
+// measures real tokens in content (each entry either literal text or a
+// path to a file to read and count) using tokenizer.New's default
+// encoding; passing estimate=true instead falls back to the old
+// (searches*50)+(filesRead*1000)+(filesEdited*500) heuristic, for callers
+// that don't have the actual content handy. estimated_tokens_used is
+// always recorded either way, so CmdTokenDrift can compare the two.
+func (a *App) CmdAddTokenMetrics(sessionID, searches, filesRead, filesEdited int, estimate bool, content []string) error {
 	if sessionID <= 0 {
 		return errors.New("session_id is required")
 	}
@@ -944,41 +1248,128 @@ func (a *App) CmdAddTokenMetrics(sessionID, searches, filesRead, filesEdited int
 		saved = 0
 	}
 
-	sql := fmt.Sprintf(`INSERT INTO token_metrics (session_id, searches_count, files_read_count, files_edited_count, estimated_tokens_used, estimated_tokens_without, tokens_saved)
-VALUES (%d, %d, %d, %d, %d, %d, %d);`, sessionID, searches, filesRead, filesEdited, estimatedUsed, estimatedWithout, saved)
-	if _, err := a.DB.RunSQL(sql); err != nil {
+	var measuredUsed int
+	if !estimate && len(content) > 0 {
+		n, err := a.measureTokens(content)
+		if err != nil {
+			return err
+		}
+		measuredUsed = n
+	}
+
+	chargeUsed := estimatedUsed
+	if measuredUsed > 0 {
+		chargeUsed = measuredUsed
+	}
+	if err := a.chargeBudget(sessionID, chargeUsed); err != nil {
+		return err
+	}
+
+	_, err := a.Store.Insert("token_metrics", map[string]interface{}{
+		"session_id":               sessionID,
+		"searches_count":           searches,
+		"files_read_count":         filesRead,
+		"files_edited_count":       filesEdited,
+		"estimated_tokens_used":    estimatedUsed,
+		"estimated_tokens_without": estimatedWithout,
+		"tokens_saved":             saved,
+		"measured_tokens_used":     measuredUsed,
+	})
+	if err != nil {
 		return err
 	}
 	fmt.Println("Token metrics saved.")
 	return nil
 }
 
-func (a *App) CmdTokenStats() error {
+// measureTokens sums tokenizer.New's default-encoding token count across
+// content, reading each entry from disk first if it names an existing
+// file, otherwise counting it as literal text.
+func (a *App) measureTokens(content []string) (int, error) {
+	tok, err := tokenizer.New("")
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, c := range content {
+		text := c
+		if data, err := os.ReadFile(c); err == nil {
+			text = string(data)
+		}
+		n, err := tok.Count(text)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// tokenMetricsTotals is the set of aggregates both CmdTokenStats (as JSON)
+// and CmdServeMetrics (as Prometheus gauges) publish, summed from every
+// token_metrics row.
+type tokenMetricsTotals struct {
+	tracked                                int
+	searches, filesRead, filesEdited       int64
+	tokensUsed, tokensWithout, tokensSaved int64
+}
+
+// tokenMetricsAggregates sums token_metrics into a tokenMetricsTotals, or a
+// zeroed one if there's no database yet or no rows have been recorded.
+func (a *App) tokenMetricsAggregates() (tokenMetricsTotals, error) {
 	if !a.dbExists() {
-		fmt.Print(`{"tracked_sessions":0,"total_searches":0,"total_files_read":0,"total_files_edited":0,"total_tokens_used":0,"total_tokens_without":0,"total_tokens_saved":0}`)
-		return nil
+		return tokenMetricsTotals{}, nil
 	}
 	a.ensureTokenMetricsTable()
 
-	sql := `SELECT COUNT(*) as tracked_sessions,
-       COALESCE(SUM(searches_count), 0),
-       COALESCE(SUM(files_read_count), 0),
-       COALESCE(SUM(files_edited_count), 0),
-       COALESCE(SUM(estimated_tokens_used), 0),
-       COALESCE(SUM(estimated_tokens_without), 0),
-       COALESCE(SUM(tokens_saved), 0)
-FROM token_metrics;`
-	out, err := a.DB.Run("-separator", "\t", sql)
+	rows, err := a.Store.Query("token_metrics", []string{
+		"searches_count", "files_read_count", "files_edited_count",
+		"estimated_tokens_used", "estimated_tokens_without", "tokens_saved",
+	}, "")
+	if err != nil {
+		return tokenMetricsTotals{}, err
+	}
+
+	var t tokenMetricsTotals
+	t.tracked = len(rows)
+	for _, r := range rows {
+		t.searches += rowInt(r, "searches_count")
+		t.filesRead += rowInt(r, "files_read_count")
+		t.filesEdited += rowInt(r, "files_edited_count")
+		t.tokensUsed += rowInt(r, "estimated_tokens_used")
+		t.tokensWithout += rowInt(r, "estimated_tokens_without")
+		t.tokensSaved += rowInt(r, "tokens_saved")
+	}
+	return t, nil
+}
+
+// CmdTokenStats builds a report.TokenStatsReport from
+// tokenMetricsAggregates and renders it via report.Render. With no
+// --format override it defaults to "json", preserving the original
+// hand-rolled JSON-blob output byte-for-byte.
+func (a *App) CmdTokenStats() error {
+	t, err := a.tokenMetricsAggregates()
 	if err != nil {
 		return err
 	}
-	parts := strings.SplitN(strings.TrimSpace(out), "\t", 7)
-	if len(parts) != 7 {
-		fmt.Print(`{"tracked_sessions":0,"total_searches":0,"total_files_read":0,"total_files_edited":0,"total_tokens_used":0,"total_tokens_without":0,"total_tokens_saved":0}`)
-		return nil
+	rpt := report.TokenStatsReport{
+		TrackedSessions:    int64(t.tracked),
+		TotalSearches:      t.searches,
+		TotalFilesRead:     t.filesRead,
+		TotalFilesEdited:   t.filesEdited,
+		TotalTokensUsed:    t.tokensUsed,
+		TotalTokensWithout: t.tokensWithout,
+		TotalTokensSaved:   t.tokensSaved,
 	}
-	fmt.Printf(`{"tracked_sessions":%s,"total_searches":%s,"total_files_read":%s,"total_files_edited":%s,"total_tokens_used":%s,"total_tokens_without":%s,"total_tokens_saved":%s}`,
-		parts[0], parts[1], parts[2], parts[3], parts[4], parts[5], parts[6])
-	fmt.Println()
+
+	format := a.Format
+	if format == "" {
+		format = "json"
+	}
+	out, err := report.Render(format, rpt)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
 	return nil
 }
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"claude-turbo-search/memorydb/internal/budget"
+	"claude-turbo-search/memorydb/internal/db"
+	"claude-turbo-search/memorydb/internal/models"
+)
+
+// CmdSetBudget registers (or replaces) sessionID's soft/hard token budget in
+// session_budgets. Existing watermarks (used/peak/actions_fired) are kept,
+// so re-running set-budget to adjust a limit doesn't reset progress.
+func (a *App) CmdSetBudget(sessionID int, softLimit, hardLimit int64) error {
+	if sessionID <= 0 {
+		return errors.New("session_id is required")
+	}
+	if softLimit <= 0 || hardLimit <= 0 || softLimit > hardLimit {
+		return errors.New("soft_limit and hard_limit must be positive, with soft_limit <= hard_limit")
+	}
+	if err := a.EnsureSchema(); err != nil {
+		return err
+	}
+	if _, err := a.Store.Exec(
+		"INSERT OR IGNORE INTO session_budgets (session_id, soft_limit, hard_limit) VALUES (?, ?, ?);",
+		sessionID, softLimit, hardLimit); err != nil {
+		return err
+	}
+	_, err := a.Store.Exec(
+		"UPDATE session_budgets SET soft_limit = ?, hard_limit = ?, updated_at = CURRENT_TIMESTAMP WHERE session_id = ?;",
+		softLimit, hardLimit, sessionID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Budget set for session %d: soft=%d hard=%d\n", sessionID, softLimit, hardLimit)
+	return nil
+}
+
+// CmdCheckBudget prints sessionID's current budget watermarks as JSON, or a
+// zeroed report if no budget has been registered for it.
+func (a *App) CmdCheckBudget(sessionID int) error {
+	if sessionID <= 0 {
+		return errors.New("session_id is required")
+	}
+	if !a.dbExists() || !a.Store.HasTable("session_budgets") {
+		fmt.Printf(`{"session_id":%d,"soft_limit":0,"hard_limit":0,"used":0,"peak_usage":0,"actions_fired":0}`, sessionID)
+		fmt.Println()
+		return nil
+	}
+	b, ok, err := a.loadSessionBudget(sessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Printf(`{"session_id":%d,"soft_limit":0,"hard_limit":0,"used":0,"peak_usage":0,"actions_fired":0}`, sessionID)
+		fmt.Println()
+		return nil
+	}
+	fmt.Printf(`{"session_id":%d,"soft_limit":%d,"hard_limit":%d,"used":%d,"peak_usage":%d,"actions_fired":%d}`,
+		b.SessionID, b.SoftLimit, b.HardLimit, b.Used, b.PeakUsage, b.ActionsFired)
+	fmt.Println()
+	return nil
+}
+
+// loadSessionBudget reads sessionID's session_budgets row, if one exists.
+func (a *App) loadSessionBudget(sessionID int) (models.SessionBudgetRow, bool, error) {
+	rows, err := db.Scan[models.SessionBudgetRow](a.DB,
+		"SELECT session_id, soft_limit, hard_limit, used, peak_usage, actions_fired FROM session_budgets WHERE session_id = ?;",
+		sessionID)
+	if err != nil {
+		return models.SessionBudgetRow{}, false, err
+	}
+	if len(rows) == 0 {
+		return models.SessionBudgetRow{}, false, nil
+	}
+	return rows[0], true, nil
+}
+
+// saveSessionBudget persists t's watermarks back to sessionID's
+// session_budgets row.
+func (a *App) saveSessionBudget(t *budget.BudgetTracker) error {
+	_, err := a.Store.Exec(
+		"UPDATE session_budgets SET used = ?, peak_usage = ?, actions_fired = ?, updated_at = CURRENT_TIMESTAMP WHERE session_id = ?;",
+		t.Used, t.PeakUsage, t.ActionsFired, t.SessionID)
+	return err
+}
+
+// chargeBudget adds estimatedUsed to sessionID's budget, if one is
+// registered, firing the default action chain (see defaultBudgetActions) on
+// any threshold crossing and persisting the resulting watermarks. Sessions
+// with no registered budget are left untracked — chargeBudget is a no-op
+// for them, same as before this feature existed.
+func (a *App) chargeBudget(sessionID int, estimatedUsed int) error {
+	if !a.Store.HasTable("session_budgets") {
+		return nil
+	}
+	b, ok, err := a.loadSessionBudget(sessionID)
+	if err != nil || !ok {
+		return err
+	}
+	tracker := budget.NewBudgetTracker(sessionID, b.SoftLimit, b.HardLimit, b.Used, b.PeakUsage, b.ActionsFired)
+	for _, action := range defaultBudgetActions(a) {
+		tracker.Register(action)
+	}
+	recordErr := tracker.Record(int64(estimatedUsed))
+	if err := a.saveSessionBudget(tracker); err != nil {
+		return err
+	}
+	return recordErr
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+
+	"claude-turbo-search/memorydb/internal/budget"
+)
+
+// logBudgetAction always runs first and never frees anything: it just
+// surfaces the crossing to stderr-equivalent stdout, so the tracker falls
+// straight through to the actions that actually free headroom.
+type logBudgetAction struct{}
+
+func (logBudgetAction) Name() string  { return "log" }
+func (logBudgetAction) Priority() int { return 0 }
+func (logBudgetAction) Action(sessionID int, used int64) (int64, error) {
+	fmt.Printf("[budget] session %d: token usage at %d\n", sessionID, used)
+	return 0, nil
+}
+
+// compactKnowledgeAction runs the same session-merge/fact-dedup pass as
+// `memorydb consolidate`, estimating freed tokens at 200 per row removed —
+// a rough per-row estimate, not a measured figure.
+type compactKnowledgeAction struct{ app *App }
+
+func (a compactKnowledgeAction) Name() string  { return "compact-knowledge" }
+func (a compactKnowledgeAction) Priority() int { return 1 }
+func (a compactKnowledgeAction) Action(sessionID int, used int64) (int64, error) {
+	_, removed, err := a.app.consolidate()
+	if err != nil {
+		return 0, err
+	}
+	return int64(removed) * 200, nil
+}
+
+// trimEmbeddingQueueAction deletes all but the most recent
+// keepPendingEmbeddings pending rows from embedding_queue, freeing the tokens their queued
+// content would otherwise cost to re-embed later.
+type trimEmbeddingQueueAction struct{ app *App }
+
+const keepPendingEmbeddings = 100
+
+func (a trimEmbeddingQueueAction) Name() string  { return "trim-embedding-queue" }
+func (a trimEmbeddingQueueAction) Priority() int { return 2 }
+func (a trimEmbeddingQueueAction) Action(sessionID int, used int64) (int64, error) {
+	n, err := a.app.Store.CountRows("embedding_queue", "status = 'pending'")
+	if err != nil || n <= keepPendingEmbeddings {
+		return 0, err
+	}
+	trimmed := n - keepPendingEmbeddings
+	_, err = a.app.Store.Exec(fmt.Sprintf(
+		`DELETE FROM embedding_queue WHERE status = 'pending' AND id NOT IN (
+    SELECT id FROM embedding_queue WHERE status = 'pending' ORDER BY id DESC LIMIT %d
+);`, keepPendingEmbeddings))
+	if err != nil {
+		return 0, err
+	}
+	return int64(trimmed) * 50, nil
+}
+
+// refuseInsertsAction is the last, lowest-priority link in the default
+// chain: it frees nothing, so if usage is still at or above HardLimit once
+// every other action has had a turn, BudgetTracker.Record reports
+// *budget.ErrBudgetExceeded to the caller instead of silently continuing.
+type refuseInsertsAction struct{}
+
+func (refuseInsertsAction) Name() string  { return "refuse-inserts" }
+func (refuseInsertsAction) Priority() int { return 99 }
+func (refuseInsertsAction) Action(sessionID int, used int64) (int64, error) {
+	return 0, nil
+}
+
+// defaultBudgetActions is the action chain every BudgetTracker built by
+// CmdAddTokenMetrics registers, in the order the request asked for: log,
+// compact old knowledge rows, trim the embedding queue, then refuse.
+func defaultBudgetActions(a *App) []budget.BudgetAction {
+	return []budget.BudgetAction{
+		logBudgetAction{},
+		compactKnowledgeAction{app: a},
+		trimEmbeddingQueueAction{app: a},
+		refuseInsertsAction{},
+	}
+}
@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"claude-turbo-search/memorydb/internal/db"
+)
+
+// ── Graph export (dot / graphml / json) ─────────────────────────────────
+
+type kgGraphNode struct {
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	RefCount int    `json:"ref_count"`
+}
+
+type kgGraphEdge struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Kind   string  `json:"kind"`
+	Weight float64 `json:"weight"`
+}
+
+type kgGraph struct {
+	Nodes []kgGraphNode `json:"nodes"`
+	Edges []kgGraphEdge `json:"edges"`
+}
+
+type kgExportOpts struct {
+	format     string
+	output     string
+	minRefs    int
+	entityType string
+	since      string
+}
+
+func kgParseExportOpts(args []string) (kgExportOpts, error) {
+	opts := kgExportOpts{format: "dot"}
+	for _, a := range args {
+		k, v, ok := strings.Cut(a, "=")
+		if !ok {
+			return opts, fmt.Errorf("expected --flag=value, got %q", a)
+		}
+		switch k {
+		case "--format":
+			opts.format = v
+		case "--output":
+			opts.output = v
+		case "--min-refs":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid --min-refs value %q", v)
+			}
+			opts.minRefs = n
+		case "--entity-type":
+			opts.entityType = v
+		case "--since":
+			opts.since = v
+		default:
+			return opts, fmt.Errorf("unknown export flag %q", k)
+		}
+	}
+	switch opts.format {
+	case "dot", "graphml", "json":
+	default:
+		return opts, fmt.Errorf("unknown export format %q (use: dot, graphml, json)", opts.format)
+	}
+	return opts, nil
+}
+
+func kgNodeID(kind, typ, name string) string {
+	return kind + ":" + typ + ":" + name
+}
+
+// kgBuildExportGraph normalizes entities, relations and co-occurrences into
+// a single node/edge graph, applying the requested filters along the way.
+func (a *App) kgBuildExportGraph(opts kgExportOpts) kgGraph {
+	g := kgGraph{}
+	nodeIndex := map[string]int{}
+
+	addNode := func(id, label, typ string, refs int) {
+		if idx, ok := nodeIndex[id]; ok {
+			if refs > g.Nodes[idx].RefCount {
+				g.Nodes[idx].RefCount = refs
+			}
+			return
+		}
+		nodeIndex[id] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, kgGraphNode{ID: id, Label: label, Type: typ, RefCount: refs})
+	}
+
+	entityID := func(e kgEntity) string { return kgNodeID("entity", e.eType, e.entity) }
+
+	allowed := map[string]bool{}
+	for _, e := range a.kgGetTopEntities(5000) {
+		if opts.entityType != "" && e.eType != opts.entityType {
+			continue
+		}
+		if e.refCount < opts.minRefs {
+			continue
+		}
+		allowed[entityID(e)] = true
+		addNode(entityID(e), e.entity, e.eType, e.refCount)
+	}
+
+	for _, rel := range a.kgGetRelationsSince(opts.since, 2000) {
+		fromID := kgNodeID("src", rel.fromType, fmt.Sprintf("%d", rel.fromID))
+		toID := kgNodeID("src", rel.toType, fmt.Sprintf("%d", rel.toID))
+		fromLabel := rel.fromLabel
+		if fromLabel == "" {
+			fromLabel = fmt.Sprintf("%s:%d", rel.fromType, rel.fromID)
+		}
+		toLabel := rel.toLabel
+		if toLabel == "" {
+			toLabel = fmt.Sprintf("%s:%d", rel.toType, rel.toID)
+		}
+		addNode(fromID, fromLabel, rel.fromType, 0)
+		addNode(toID, toLabel, rel.toType, 0)
+		g.Edges = append(g.Edges, kgGraphEdge{From: fromID, To: toID, Kind: "relation:" + rel.relation, Weight: 1})
+	}
+
+	for _, co := range a.kgGetCoOccurrences(2000) {
+		aID := kgNodeID("entity", co.typeA, co.entityA)
+		bID := kgNodeID("entity", co.typeB, co.entityB)
+		if len(allowed) > 0 && (!allowed[aID] || !allowed[bID]) {
+			continue
+		}
+		if _, ok := nodeIndex[aID]; !ok {
+			continue
+		}
+		if _, ok := nodeIndex[bID]; !ok {
+			continue
+		}
+		g.Edges = append(g.Edges, kgGraphEdge{From: aID, To: bID, Kind: "cooccurs", Weight: float64(co.sharedSources)})
+	}
+
+	return g
+}
+
+// kgGetRelationsSince is kgGetRelations with an optional created_at floor
+// and a caller-supplied row cap, used by the exporter.
+func (a *App) kgGetRelationsSince(since string, limit int) []kgRelation {
+	if !a.DB.HasTable("entry_relations") {
+		return nil
+	}
+	filter := ""
+	if strings.TrimSpace(since) != "" {
+		filter = fmt.Sprintf(" WHERE er.created_at >= '%s'", db.SQLQuote(since))
+	}
+	sql := fmt.Sprintf(`SELECT
+    er.from_type, er.from_id, er.to_type, er.to_id, er.relation,
+    CASE er.from_type
+        WHEN 'session'   THEN (SELECT SUBSTR(summary, 1, 60) FROM sessions WHERE id = er.from_id)
+        WHEN 'knowledge' THEN (SELECT area FROM knowledge WHERE id = er.from_id)
+        WHEN 'fact'      THEN (SELECT SUBSTR(fact, 1, 60) FROM facts WHERE id = er.from_id)
+    END,
+    CASE er.to_type
+        WHEN 'session'   THEN (SELECT SUBSTR(summary, 1, 60) FROM sessions WHERE id = er.to_id)
+        WHEN 'knowledge' THEN (SELECT area FROM knowledge WHERE id = er.to_id)
+        WHEN 'fact'      THEN (SELECT SUBSTR(fact, 1, 60) FROM facts WHERE id = er.to_id)
+    END
+FROM entry_relations er%s
+ORDER BY er.created_at DESC
+LIMIT %d;`, filter, limit)
+	out, err := a.DB.Run("-separator", "\t", sql)
+	if err != nil {
+		return nil
+	}
+	var result []kgRelation
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 7)
+		if len(parts) < 5 {
+			continue
+		}
+		fromID, _ := strconv.Atoi(parts[1])
+		toID, _ := strconv.Atoi(parts[3])
+		fromLabel, toLabel := "", ""
+		if len(parts) > 5 {
+			fromLabel = parts[5]
+		}
+		if len(parts) > 6 {
+			toLabel = parts[6]
+		}
+		result = append(result, kgRelation{
+			fromType: parts[0], fromID: fromID,
+			toType: parts[2], toID: toID,
+			relation: parts[4], fromLabel: fromLabel, toLabel: toLabel,
+		})
+	}
+	return result
+}
+
+func kgRenderDOT(g kgGraph) string {
+	var b strings.Builder
+	b.WriteString("digraph knowledge_graph {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		b.WriteString(fmt.Sprintf("  %q [label=%q, color=%q, fillcolor=%q, style=filled, fontsize=10];\n",
+			n.ID, n.Label, kgDotColor(n.Type), kgDotFillColor(n.Type)))
+	}
+	for _, e := range g.Edges {
+		b.WriteString(fmt.Sprintf("  %q -> %q [label=%q, weight=%.2f];\n", e.From, e.To, e.Kind, e.Weight))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var kgDotPalette = map[string]string{
+	"file":      "#1f77b4",
+	"concept":   "#ff7f0e",
+	"package":   "#2ca02c",
+	"function":  "#d62728",
+	"session":   "#17becf",
+	"knowledge": "#2ca02c",
+	"fact":      "#bcbd22",
+}
+
+func kgDotColor(entityType string) string {
+	if c, ok := kgDotPalette[entityType]; ok {
+		return c
+	}
+	return "#7f7f7f"
+}
+
+func kgDotFillColor(entityType string) string {
+	return kgDotColor(entityType) + "33"
+}
+
+func kgRenderGraphML(g kgGraph) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="d_type" for="node" attr.name="entity_type" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="d_refs" for="node" attr.name="ref_count" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="d_label" for="node" attr.name="label" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="d_relation" for="edge" attr.name="relation" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="d_weight" for="edge" attr.name="weight" attr.type="double"/>` + "\n")
+	b.WriteString(`  <graph id="G" edgedefault="directed">` + "\n")
+	for _, n := range g.Nodes {
+		b.WriteString(fmt.Sprintf(`    <node id="%s">`+"\n", kgXMLEscape(n.ID)))
+		b.WriteString(fmt.Sprintf(`      <data key="d_type">%s</data>`+"\n", kgXMLEscape(n.Type)))
+		b.WriteString(fmt.Sprintf(`      <data key="d_refs">%d</data>`+"\n", n.RefCount))
+		b.WriteString(fmt.Sprintf(`      <data key="d_label">%s</data>`+"\n", kgXMLEscape(n.Label)))
+		b.WriteString("    </node>\n")
+	}
+	for _, e := range g.Edges {
+		b.WriteString(fmt.Sprintf(`    <edge source="%s" target="%s">`+"\n", kgXMLEscape(e.From), kgXMLEscape(e.To)))
+		b.WriteString(fmt.Sprintf(`      <data key="d_relation">%s</data>`+"\n", kgXMLEscape(e.Kind)))
+		b.WriteString(fmt.Sprintf(`      <data key="d_weight">%.4f</data>`+"\n", e.Weight))
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+	return b.String()
+}
+
+func kgXMLEscape(s string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	return replacer.Replace(s)
+}
+
+// kgExport builds the normalized graph and writes it in the requested
+// format to stdout or --output.
+func (a *App) kgExport(args []string) error {
+	opts, err := kgParseExportOpts(args)
+	if err != nil {
+		return err
+	}
+	g := a.kgBuildExportGraph(opts)
+
+	var out string
+	switch opts.format {
+	case "dot":
+		out = kgRenderDOT(g)
+	case "graphml":
+		out = kgRenderGraphML(g)
+	case "json":
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = string(b) + "\n"
+	}
+
+	if opts.output == "" {
+		fmt.Print(out)
+		return nil
+	}
+	if err := os.WriteFile(opts.output, []byte(out), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", opts.output, err)
+	}
+	fmt.Printf("Exported %d nodes, %d edges to %s\n", len(g.Nodes), len(g.Edges), opts.output)
+	return nil
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureGraph exercises kgRenderDOT/kgRenderGraphML/json output for a
+// small graph, including a label with characters that must be escaped by
+// kgXMLEscape in the graphml case.
+var fixtureGraph = kgGraph{
+	Nodes: []kgGraphNode{
+		{ID: "entity:function:parse<query>", Label: `parse<query> & "friends"`, Type: "function", RefCount: 3},
+		{ID: "entity:concept:auth", Label: "auth", Type: "concept", RefCount: 1},
+	},
+	Edges: []kgGraphEdge{
+		{From: "entity:function:parse<query>", To: "entity:concept:auth", Kind: "cooccurs", Weight: 2},
+	},
+}
+
+func TestKgRenderGolden(t *testing.T) {
+	cases := []struct {
+		name   string
+		render func(kgGraph) string
+	}{
+		{"dot", kgRenderDOT},
+		{"graphml", kgRenderGraphML},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.render(fixtureGraph)
+			goldenPath := filepath.Join("testdata", "kg_export_"+c.name+".golden")
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading %s: %v", goldenPath, err)
+			}
+			if got != string(want) {
+				t.Errorf("render %s = %q, want %q", c.name, got, string(want))
+			}
+		})
+	}
+}
+
+func TestKgRenderGraphMLEscapesAttributes(t *testing.T) {
+	out := kgRenderGraphML(fixtureGraph)
+	if want := `<node id="entity:function:parse&lt;query&gt;">`; !strings.Contains(out, want) {
+		t.Errorf("expected escaped node id %q in:\n%s", want, out)
+	}
+	if want := `&lt;query&gt; &amp; &quot;friends&quot;`; !strings.Contains(out, want) {
+		t.Errorf("expected escaped label %q in:\n%s", want, out)
+	}
+}
+
+func TestKgExportJSONGolden(t *testing.T) {
+	b, err := json.MarshalIndent(fixtureGraph, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent: %v", err)
+	}
+	got := string(b) + "\n"
+	goldenPath := filepath.Join("testdata", "kg_export_json.golden")
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("reading %s: %v", goldenPath, err)
+	}
+	if got != string(want) {
+		t.Errorf("json export = %q, want %q", got, string(want))
+	}
+}
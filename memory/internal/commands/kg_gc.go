@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-turbo-search/memorydb/internal/db"
+)
+
+// ── Retention / garbage collection ──────────────────────────────────────
+
+// GCPolicy controls what CmdKnowledgeGraphGC prunes and how.
+type GCPolicy struct {
+	DryRun    bool
+	Vacuum    bool
+	MinAge    string   // e.g. "30 days", fed straight into SQLite's datetime('now', '-'||MinAge)
+	Stopwords []string
+	GCLease   time.Duration // if > 0, CmdKnowledgeGraphGC runs forever on this interval
+}
+
+var sourceTable = map[string]string{
+	"session":   "sessions",
+	"knowledge": "knowledge",
+	"fact":      "facts",
+}
+
+// gcAuditRecord is the JSON audit trail emitted after a (non-dry-run) sweep
+// so a too-aggressive policy can be reviewed or reversed by hand.
+type gcAuditRecord struct {
+	RanAt              string   `json:"ran_at"`
+	DryRun             bool     `json:"dry_run"`
+	DanglingEntities   int      `json:"dangling_entity_metadata_deleted"`
+	StaleSingleRefs    int      `json:"stale_single_ref_entities_deleted"`
+	DanglingRelations  int      `json:"dangling_entry_relations_deleted"`
+	StopwordEntities   int      `json:"stopword_entities_deleted"`
+	AffectedEntityList []string `json:"affected_entities,omitempty"`
+}
+
+// minAgePattern is the only shape kgFindStaleSingleRefEntities' datetime()
+// interpolation accepts: a positive integer and a SQLite datetime modifier
+// unit. Anything else (including a --min-age value that tries to break out
+// of the string literal) is rejected at parse time rather than escaped.
+var minAgePattern = regexp.MustCompile(`^\d+ (second|minute|hour|day|month|year)s?$`)
+
+func kgParseGCPolicy(extra []string) (GCPolicy, error) {
+	p := GCPolicy{MinAge: "30 days"}
+	for _, a := range extra {
+		switch {
+		case a == "--dry-run":
+			p.DryRun = true
+		case a == "--vacuum":
+			p.Vacuum = true
+		case strings.HasPrefix(a, "--min-age="):
+			minAge := strings.TrimPrefix(a, "--min-age=")
+			if !minAgePattern.MatchString(minAge) {
+				return p, fmt.Errorf("invalid --min-age value %q, want \"<n> seconds|minutes|hours|days|months|years\"", minAge)
+			}
+			p.MinAge = minAge
+		case strings.HasPrefix(a, "--stopwords="):
+			p.Stopwords = parseCSV(strings.TrimPrefix(a, "--stopwords="))
+		case strings.HasPrefix(a, "--gc-lease="):
+			d, err := time.ParseDuration(strings.TrimPrefix(a, "--gc-lease="))
+			if err != nil {
+				return p, fmt.Errorf("invalid --gc-lease value: %w", err)
+			}
+			p.GCLease = d
+		default:
+			return p, fmt.Errorf("unknown gc flag %q", a)
+		}
+	}
+	return p, nil
+}
+
+// CmdKnowledgeGraphGC prunes entity_metadata and entry_relations rows under
+// the given policy. With GCLease set it runs forever, sweeping once per
+// lease interval, like a long-lived background collector.
+func (a *App) CmdKnowledgeGraphGC(policy GCPolicy) error {
+	if policy.GCLease > 0 {
+		for {
+			if err := a.kgRunGCSweep(policy); err != nil {
+				fmt.Println("gc sweep failed:", err)
+			}
+			time.Sleep(policy.GCLease)
+		}
+	}
+	return a.kgRunGCSweep(policy)
+}
+
+func (a *App) kgRunGCSweep(policy GCPolicy) error {
+	if !a.DB.HasTable("entity_metadata") {
+		fmt.Println(dim("entity_metadata not present; nothing to collect."))
+		return nil
+	}
+
+	dangling := a.kgFindDanglingEntityMetadata()
+	staleSingles := a.kgFindStaleSingleRefEntities(policy.MinAge)
+	danglingRelations := a.kgFindDanglingRelations()
+	stopwordHits := a.kgFindStopwordEntities(policy.Stopwords)
+
+	record := gcAuditRecord{
+		RanAt:             time.Now().UTC().Format(time.RFC3339),
+		DryRun:            policy.DryRun,
+		DanglingEntities:  len(dangling),
+		StaleSingleRefs:   len(staleSingles),
+		DanglingRelations: len(danglingRelations),
+		StopwordEntities:  len(stopwordHits),
+	}
+
+	if policy.DryRun {
+		rule(colored("GC Dry Run", ansiYellow))
+		fmt.Printf("  would delete %d entity_metadata rows (dangling source)\n", len(dangling))
+		fmt.Printf("  would delete %d entity_metadata rows (single-ref, older than %s)\n", len(staleSingles), policy.MinAge)
+		fmt.Printf("  would delete %d entry_relations rows (dangling endpoint)\n", len(danglingRelations))
+		fmt.Printf("  would delete %d entity_metadata rows (stopword match)\n", len(stopwordHits))
+		return nil
+	}
+
+	for _, id := range dangling {
+		_, _ = a.DB.RunSQL(fmt.Sprintf("DELETE FROM entity_metadata WHERE id=%d;", id))
+	}
+	for _, id := range staleSingles {
+		_, _ = a.DB.RunSQL(fmt.Sprintf("DELETE FROM entity_metadata WHERE id=%d;", id))
+	}
+	for _, id := range danglingRelations {
+		_, _ = a.DB.RunSQL(fmt.Sprintf("DELETE FROM entry_relations WHERE id=%d;", id))
+	}
+	for _, id := range stopwordHits {
+		_, _ = a.DB.RunSQL(fmt.Sprintf("DELETE FROM entity_metadata WHERE id=%d;", id))
+	}
+
+	if policy.Vacuum {
+		_, _ = a.DB.RunSQL("VACUUM;")
+	}
+
+	audit, err := json.Marshal(record)
+	if err == nil {
+		fmt.Println(string(audit))
+	}
+	return nil
+}
+
+// kgFindDanglingEntityMetadata returns entity_metadata row ids whose
+// source_id no longer exists in the table its source_type refers to.
+func (a *App) kgFindDanglingEntityMetadata() []int {
+	var ids []int
+	for sourceType, table := range sourceTable {
+		sql := fmt.Sprintf(`SELECT em.id FROM entity_metadata em
+WHERE em.source_type = '%s'
+  AND NOT EXISTS (SELECT 1 FROM %s WHERE id = em.source_id);`, db.SQLQuote(sourceType), table)
+		ids = append(ids, a.kgScanIDs(sql)...)
+	}
+	return ids
+}
+
+// kgFindStaleSingleRefEntities returns entity_metadata row ids for entities
+// referenced exactly once, older than minAge.
+func (a *App) kgFindStaleSingleRefEntities(minAge string) []int {
+	sql := fmt.Sprintf(`SELECT em.id FROM entity_metadata em
+WHERE em.created_at < datetime('now', '-%s')
+  AND (SELECT COUNT(*) FROM entity_metadata em2
+       WHERE em2.entity = em.entity AND em2.entity_type = em.entity_type) = 1;`, minAge)
+	return a.kgScanIDs(sql)
+}
+
+// kgFindDanglingRelations returns entry_relations row ids where either
+// endpoint no longer exists in its source table.
+func (a *App) kgFindDanglingRelations() []int {
+	if !a.DB.HasTable("entry_relations") {
+		return nil
+	}
+	var conds []string
+	for sourceType, table := range sourceTable {
+		conds = append(conds, fmt.Sprintf(
+			"(from_type = '%s' AND NOT EXISTS (SELECT 1 FROM %s WHERE id = from_id))",
+			db.SQLQuote(sourceType), table))
+		conds = append(conds, fmt.Sprintf(
+			"(to_type = '%s' AND NOT EXISTS (SELECT 1 FROM %s WHERE id = to_id))",
+			db.SQLQuote(sourceType), table))
+	}
+	sql := fmt.Sprintf("SELECT id FROM entry_relations WHERE %s;", strings.Join(conds, " OR "))
+	return a.kgScanIDs(sql)
+}
+
+// kgFindStopwordEntities returns entity_metadata row ids whose entity value
+// matches (case-insensitively) one of the given stopwords.
+func (a *App) kgFindStopwordEntities(stopwords []string) []int {
+	if len(stopwords) == 0 {
+		return nil
+	}
+	var conds []string
+	for _, w := range stopwords {
+		conds = append(conds, fmt.Sprintf("LOWER(entity) = '%s'", db.SQLQuote(strings.ToLower(w))))
+	}
+	sql := fmt.Sprintf("SELECT id FROM entity_metadata WHERE %s;", strings.Join(conds, " OR "))
+	return a.kgScanIDs(sql)
+}
+
+func (a *App) kgScanIDs(sql string) []int {
+	out, err := a.DB.Run(sql)
+	if err != nil {
+		return nil
+	}
+	var ids []int
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if id, err := strconv.Atoi(line); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,394 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"claude-turbo-search/memorydb/internal/db"
+)
+
+// ── Datalog-ish query language ───────────────────────────────────────────
+//
+// A program is a handful of lines:
+//
+//	:find [?fn ?src]
+//	[?fn :type "function"]
+//	[?fn :source ?src]
+//	:limit 20
+//	:order-by ?fn
+//
+// Clauses are joined by implicit conjunction on shared variable names.
+// Supported clause shapes:
+//
+//	[?var :type "TYPE"]            entity_metadata.entity_type = TYPE
+//	[?e   :source ?s]              entity_metadata source of ?e, bound to ?s
+//	[?a   :cooccurs ?b]            ?a and ?b share a source
+//	[?from :relation "REL" ?to]    entry_relations edge from ?from to ?to
+
+type kgQueryClauseKind int
+
+const (
+	kgClauseType kgQueryClauseKind = iota
+	kgClauseSource
+	kgClauseCooccurs
+	kgClauseRelation
+)
+
+type kgQueryClause struct {
+	kind kgQueryClauseKind
+	args []string // tokens with surrounding quotes stripped off string literals
+}
+
+type kgQuery struct {
+	find    []string
+	clauses []kgQueryClause
+	limit   int
+	orderBy string
+}
+
+// kgParseQuery parses a query program into an AST, or returns an error
+// describing the first malformed line.
+func kgParseQuery(program string) (*kgQuery, error) {
+	q := &kgQuery{limit: 50}
+	for _, raw := range strings.Split(program, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || line == ":where" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, ":find"):
+			vars, err := kgParseVarList(strings.TrimSpace(strings.TrimPrefix(line, ":find")))
+			if err != nil {
+				return nil, fmt.Errorf(":find %w", err)
+			}
+			q.find = vars
+		case strings.HasPrefix(line, ":limit"):
+			n := strings.TrimSpace(strings.TrimPrefix(line, ":limit"))
+			var limit int
+			if _, err := fmt.Sscanf(n, "%d", &limit); err != nil || limit <= 0 {
+				return nil, fmt.Errorf("invalid :limit value %q", n)
+			}
+			q.limit = limit
+		case strings.HasPrefix(line, ":order-by"):
+			v := strings.TrimSpace(strings.TrimPrefix(line, ":order-by"))
+			if !strings.HasPrefix(v, "?") {
+				return nil, fmt.Errorf("invalid :order-by variable %q", v)
+			}
+			q.orderBy = v
+		case strings.HasPrefix(line, "["):
+			clause, err := kgParseClause(line)
+			if err != nil {
+				return nil, err
+			}
+			q.clauses = append(q.clauses, clause)
+		default:
+			return nil, fmt.Errorf("unrecognized query line: %q", line)
+		}
+	}
+	if len(q.find) == 0 {
+		return nil, fmt.Errorf(":find is required")
+	}
+	if len(q.clauses) == 0 {
+		return nil, fmt.Errorf("query has no clauses")
+	}
+	return q, nil
+}
+
+func kgParseVarList(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("needs at least one ?variable")
+	}
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "?") {
+			return nil, fmt.Errorf("%q is not a ?variable", f)
+		}
+	}
+	return fields, nil
+}
+
+func kgParseClause(line string) (kgQueryClause, error) {
+	inner := strings.TrimSpace(line)
+	if !strings.HasPrefix(inner, "[") || !strings.HasSuffix(inner, "]") {
+		return kgQueryClause{}, fmt.Errorf("malformed clause: %q", line)
+	}
+	inner = strings.TrimSuffix(strings.TrimPrefix(inner, "["), "]")
+	tokens, err := kgTokenize(inner)
+	if err != nil {
+		return kgQueryClause{}, fmt.Errorf("clause %q: %w", line, err)
+	}
+	if len(tokens) < 3 {
+		return kgQueryClause{}, fmt.Errorf("clause %q: too few tokens", line)
+	}
+	switch tokens[1] {
+	case ":type":
+		if len(tokens) != 3 || !strings.HasPrefix(tokens[0], "?") {
+			return kgQueryClause{}, fmt.Errorf("clause %q: expected [?var :type \"TYPE\"]", line)
+		}
+		return kgQueryClause{kind: kgClauseType, args: tokens}, nil
+	case ":source":
+		if len(tokens) != 3 || !strings.HasPrefix(tokens[0], "?") || !strings.HasPrefix(tokens[2], "?") {
+			return kgQueryClause{}, fmt.Errorf("clause %q: expected [?e :source ?s]", line)
+		}
+		return kgQueryClause{kind: kgClauseSource, args: tokens}, nil
+	case ":cooccurs":
+		if len(tokens) != 3 || !strings.HasPrefix(tokens[0], "?") || !strings.HasPrefix(tokens[2], "?") {
+			return kgQueryClause{}, fmt.Errorf("clause %q: expected [?a :cooccurs ?b]", line)
+		}
+		return kgQueryClause{kind: kgClauseCooccurs, args: tokens}, nil
+	case ":relation":
+		if len(tokens) != 4 || !strings.HasPrefix(tokens[0], "?") || !strings.HasPrefix(tokens[3], "?") {
+			return kgQueryClause{}, fmt.Errorf("clause %q: expected [?from :relation \"REL\" ?to]", line)
+		}
+		return kgQueryClause{kind: kgClauseRelation, args: tokens}, nil
+	default:
+		return kgQueryClause{}, fmt.Errorf("clause %q: unknown keyword %s", line, tokens[1])
+	}
+}
+
+// kgTokenize splits clause body on whitespace while keeping double-quoted
+// string literals intact, and strips the quotes off the result.
+func kgTokenize(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case c == ' ' && !inQuote:
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated string literal")
+	}
+	flush()
+	return tokens, nil
+}
+
+// ── Translation to SQL ───────────────────────────────────────────────────
+
+type kgQueryPlan struct {
+	from        []string          // "entity_metadata e0", "entry_relations r0", ...
+	where       []string          // extra predicates, ANDed together
+	entityAlias map[string]string // ?var -> entity_metadata alias
+	scalar      map[string]string // ?var -> raw SQL expression (non-entity bindings)
+	nextEntity  int
+	nextRel     int
+}
+
+func newKgQueryPlan() *kgQueryPlan {
+	return &kgQueryPlan{entityAlias: map[string]string{}, scalar: map[string]string{}}
+}
+
+func (p *kgQueryPlan) ensureEntityAlias(v string) string {
+	if alias, ok := p.entityAlias[v]; ok {
+		return alias
+	}
+	alias := fmt.Sprintf("e%d", p.nextEntity)
+	p.nextEntity++
+	p.from = append(p.from, fmt.Sprintf("entity_metadata %s", alias))
+	p.entityAlias[v] = alias
+	return alias
+}
+
+func (p *kgQueryPlan) columnFor(v string) (string, error) {
+	if alias, ok := p.entityAlias[v]; ok {
+		return alias + ".entity", nil
+	}
+	if expr, ok := p.scalar[v]; ok {
+		return expr, nil
+	}
+	return "", fmt.Errorf("unbound variable %s", v)
+}
+
+// kgBuildPlan walks the clauses in order, allocating a table alias per new
+// entity variable and adding join predicates whenever a variable repeats.
+func kgBuildPlan(q *kgQuery) (*kgQueryPlan, error) {
+	p := newKgQueryPlan()
+	for _, c := range q.clauses {
+		switch c.kind {
+		case kgClauseType:
+			v, typ := c.args[0], c.args[2]
+			alias := p.ensureEntityAlias(v)
+			p.where = append(p.where, fmt.Sprintf("%s.entity_type = '%s'", alias, db.SQLQuote(typ)))
+		case kgClauseSource:
+			ev, sv := c.args[0], c.args[2]
+			alias := p.ensureEntityAlias(ev)
+			expr := fmt.Sprintf("(%s.source_type || ':' || %s.source_id)", alias, alias)
+			if existing, ok := p.scalar[sv]; ok {
+				p.where = append(p.where, fmt.Sprintf("%s = %s", existing, expr))
+			} else if _, ok := p.entityAlias[sv]; ok {
+				return nil, fmt.Errorf("variable %s already bound to an entity", sv)
+			} else {
+				p.scalar[sv] = expr
+			}
+		case kgClauseCooccurs:
+			av, bv := c.args[0], c.args[2]
+			aAlias := p.ensureEntityAlias(av)
+			bAlias := p.ensureEntityAlias(bv)
+			if aAlias == bAlias {
+				return nil, fmt.Errorf(":cooccurs requires two distinct variables, got %s twice", av)
+			}
+			p.where = append(p.where,
+				fmt.Sprintf("%s.source_type = %s.source_type", aAlias, bAlias),
+				fmt.Sprintf("%s.source_id = %s.source_id", aAlias, bAlias),
+				fmt.Sprintf("%s.entity != %s.entity", aAlias, bAlias),
+			)
+		case kgClauseRelation:
+			fromV, rel, toV := c.args[0], c.args[2], c.args[3]
+			fromAlias := p.ensureEntityAlias(fromV)
+			toAlias := p.ensureEntityAlias(toV)
+			rAlias := fmt.Sprintf("r%d", p.nextRel)
+			p.nextRel++
+			p.from = append(p.from, fmt.Sprintf("entry_relations %s", rAlias))
+			p.where = append(p.where,
+				fmt.Sprintf("%s.from_type = %s.source_type", rAlias, fromAlias),
+				fmt.Sprintf("%s.from_id = %s.source_id", rAlias, fromAlias),
+				fmt.Sprintf("%s.to_type = %s.source_type", rAlias, toAlias),
+				fmt.Sprintf("%s.to_id = %s.source_id", rAlias, toAlias),
+				fmt.Sprintf("%s.relation = '%s'", rAlias, db.SQLQuote(rel)),
+			)
+		}
+	}
+	return p, nil
+}
+
+func (p *kgQueryPlan) buildSQL(q *kgQuery) (string, []string, error) {
+	selectCols := make([]string, 0, len(q.find))
+	for _, v := range q.find {
+		col, err := p.columnFor(v)
+		if err != nil {
+			return "", nil, err
+		}
+		selectCols = append(selectCols, col)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT DISTINCT ")
+	b.WriteString(strings.Join(selectCols, ", "))
+	b.WriteString("\nFROM ")
+	b.WriteString(strings.Join(p.from, ", "))
+	if len(p.where) > 0 {
+		b.WriteString("\nWHERE ")
+		b.WriteString(strings.Join(p.where, "\n  AND "))
+	}
+	if q.orderBy != "" {
+		col, err := p.columnFor(q.orderBy)
+		if err != nil {
+			return "", nil, fmt.Errorf(":order-by %w", err)
+		}
+		b.WriteString(fmt.Sprintf("\nORDER BY %s", col))
+	}
+	b.WriteString(fmt.Sprintf("\nLIMIT %d;", q.limit))
+	return b.String(), q.find, nil
+}
+
+// kgRunQuery parses, plans, executes and prints a Datalog-style program
+// against the knowledge graph tables.
+func (a *App) kgRunQuery(program string) error {
+	q, err := kgParseQuery(program)
+	if err != nil {
+		return fmt.Errorf("query parse error: %w", err)
+	}
+	plan, err := kgBuildPlan(q)
+	if err != nil {
+		return fmt.Errorf("query plan error: %w", err)
+	}
+	sql, cols, err := plan.buildSQL(q)
+	if err != nil {
+		return fmt.Errorf("query plan error: %w", err)
+	}
+
+	out, err := a.DB.Run("-separator", "\t", sql)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rows = append(rows, strings.SplitN(line, "\t", len(cols)))
+	}
+
+	rule(colored("Query Results", ansiMagenta))
+	if len(rows) == 0 {
+		fmt.Println(dim("  (no results)"))
+		return nil
+	}
+
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		widths[i] = len(c)
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if i < len(widths) && len(v) > widths[i] {
+				widths[i] = len(v)
+			}
+		}
+	}
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = colored(padRight(c, widths[i]), ansiBold)
+	}
+	fmt.Println("  " + strings.Join(header, "  "))
+	for _, row := range rows {
+		cells := make([]string, len(cols))
+		for i := range cols {
+			val := ""
+			if i < len(row) {
+				val = row[i]
+			}
+			cells[i] = padRight(val, widths[i])
+		}
+		fmt.Println("  " + strings.Join(cells, "  "))
+	}
+	return nil
+}
+
+// kgQueryREPL reads one program per blank-line-terminated block from stdin
+// and prints results until EOF or a line containing just "quit".
+func (a *App) kgQueryREPL() error {
+	fmt.Println(dim("Knowledge graph query REPL. Enter a program, blank line to run, 'quit' to exit."))
+	scanner := bufio.NewScanner(os.Stdin)
+	var lines []string
+	for {
+		fmt.Print("kg> ")
+		if !scanner.Scan() {
+			return nil
+		}
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "quit" {
+			return nil
+		}
+		if strings.TrimSpace(line) == "" {
+			if len(lines) == 0 {
+				continue
+			}
+			program := strings.Join(lines, "\n")
+			lines = nil
+			if err := a.kgRunQuery(program); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
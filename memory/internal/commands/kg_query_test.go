@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"claude-turbo-search/memorydb/internal/db"
+)
+
+func TestKgParseQueryErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		program string
+		wantErr string
+	}{
+		{"missing find", `[?fn :type "function"]`, ":find is required"},
+		{"no clauses", `:find [?fn]`, "query has no clauses"},
+		{"find not a variable", ":find [fn]\n[?fn :type \"function\"]", "is not a ?variable"},
+		{"bad limit", ":find [?fn]\n[?fn :type \"function\"]\n:limit abc", "invalid :limit value"},
+		{"zero limit", ":find [?fn]\n[?fn :type \"function\"]\n:limit 0", "invalid :limit value"},
+		{"bad order-by", ":find [?fn]\n[?fn :type \"function\"]\n:order-by fn", "invalid :order-by variable"},
+		{"unterminated string", ":find [?fn]\n[?fn :type \"function]", "unterminated string literal"},
+		{"unrecognized line", ":find [?fn]\nbogus line", "unrecognized query line"},
+		{"malformed clause brackets", ":find [?fn]\n?fn :type \"function\"", "unrecognized query line"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := kgParseQuery(c.program)
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", c.wantErr)
+			}
+			if !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("error %q does not contain %q", err.Error(), c.wantErr)
+			}
+		})
+	}
+}
+
+func TestKgParseClause(t *testing.T) {
+	cases := []struct {
+		name    string
+		line    string
+		wantErr string
+		kind    kgQueryClauseKind
+	}{
+		{"type", `[?fn :type "function"]`, "", kgClauseType},
+		{"source", `[?fn :source ?s]`, "", kgClauseSource},
+		{"cooccurs", `[?a :cooccurs ?b]`, "", kgClauseCooccurs},
+		{"relation", `[?from :relation "calls" ?to]`, "", kgClauseRelation},
+		{"type bad shape", `[?fn :type "function" extra]`, "expected [?var :type", 0},
+		{"source needs two vars", `[?fn :source "s"]`, "expected [?e :source ?s]", 0},
+		{"unknown keyword", `[?fn :bogus "x"]`, "unknown keyword", 0},
+		{"missing brackets", `?fn :type "function"`, "malformed clause", 0},
+		{"too few tokens", `[?fn :type]`, "too few tokens", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clause, err := kgParseClause(c.line)
+			if c.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", c.wantErr)
+				}
+				if !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("error %q does not contain %q", err.Error(), c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if clause.kind != c.kind {
+				t.Fatalf("got kind %v, want %v", clause.kind, c.kind)
+			}
+		})
+	}
+}
+
+func TestKgBuildPlanAndSQL(t *testing.T) {
+	q, err := kgParseQuery(":find [?fn ?src]\n[?fn :type \"function\"]\n[?fn :source ?src]\n:limit 5")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	plan, err := kgBuildPlan(q)
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	sql, cols, err := plan.buildSQL(q)
+	if err != nil {
+		t.Fatalf("build sql: %v", err)
+	}
+	if len(cols) != 2 {
+		t.Fatalf("expected 2 result columns, got %d", len(cols))
+	}
+	if !strings.Contains(sql, "entity_metadata e0") {
+		t.Fatalf("expected a single entity_metadata alias in SQL, got: %s", sql)
+	}
+	if !strings.Contains(sql, "e0.entity_type = 'function'") {
+		t.Fatalf("expected the :type clause to be translated, got: %s", sql)
+	}
+	if !strings.Contains(sql, "LIMIT 5;") {
+		t.Fatalf("expected :limit to be honored, got: %s", sql)
+	}
+}
+
+func TestKgBuildPlanUnboundOrderBy(t *testing.T) {
+	q, err := kgParseQuery(":find [?fn]\n[?fn :type \"function\"]\n:order-by ?nope")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	plan, err := kgBuildPlan(q)
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	if _, _, err := plan.buildSQL(q); err == nil || !strings.Contains(err.Error(), "unbound variable") {
+		t.Fatalf("expected an unbound variable error, got %v", err)
+	}
+}
+
+func TestKgRunQueryEmptyResult(t *testing.T) {
+	dir := t.TempDir()
+	a := &App{DBFile: filepath.Join(dir, "memory.db"), MemoryDir: dir, DB: db.New(filepath.Join(dir, "memory.db"))}
+	if err := a.EnsureSchema(); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := a.kgRunQuery(`:find [?fn]
+[?fn :type "function"]`); err != nil {
+			t.Fatalf("kgRunQuery: %v", err)
+		}
+	})
+	if !strings.Contains(out, "(no results)") {
+		t.Fatalf("expected an empty-result message, got: %s", out)
+	}
+}
+
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// everything written to it, since kgRunQuery prints straight to stdout
+// rather than returning its rendered output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+
+	w.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read pipe: %v", err)
+	}
+	return string(data)
+}
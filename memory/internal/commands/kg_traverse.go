@@ -0,0 +1,395 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// kgExtractDepthFlag looks for a "--depth N" pair in extra args and returns
+// N, or 0 if absent or malformed (0 means: use the regular 1-hop explore).
+func kgExtractDepthFlag(extra []string) int {
+	for i, a := range extra {
+		if a == "--depth" && i+1 < len(extra) {
+			if n, err := strconv.Atoi(extra[i+1]); err == nil && n > 0 {
+				return n
+			}
+		}
+		if strings.HasPrefix(a, "--depth=") {
+			if n, err := strconv.Atoi(strings.TrimPrefix(a, "--depth=")); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// ── Union graph (entry_relations ∪ co-occurrences above a threshold) ────
+
+type kgUnionEdge struct {
+	to     string
+	label  string
+	weight float64
+}
+
+const kgFrontierCap = 10000
+
+// kgBuildUnionAdjacency builds an undirected adjacency list over entity
+// names from two sources: co-occurrence pairs sharing at least coThreshold
+// sources, and entry_relations edges translated through entity_metadata so
+// both endpoints land in entity space.
+func (a *App) kgBuildUnionAdjacency(coThreshold int) map[string][]kgUnionEdge {
+	adj := map[string][]kgUnionEdge{}
+	add := func(from, to, label string, weight float64) {
+		adj[from] = append(adj[from], kgUnionEdge{to: to, label: label, weight: weight})
+	}
+
+	for _, co := range a.kgGetCoOccurrences(5000) {
+		if co.sharedSources < coThreshold {
+			continue
+		}
+		add(co.entityA, co.entityB, "cooccurs", float64(co.sharedSources))
+		add(co.entityB, co.entityA, "cooccurs", float64(co.sharedSources))
+	}
+
+	for _, e := range a.kgGetEntityRelationEdges(500) {
+		add(e.fromEntity, e.toEntity, e.relation, 1.0)
+		add(e.toEntity, e.fromEntity, e.relation, 1.0)
+	}
+
+	return adj
+}
+
+type kgEntityRelEdge struct {
+	fromEntity string
+	toEntity   string
+	relation   string
+}
+
+// kgGetEntityRelationEdges maps entry_relations rows through entity_metadata
+// so each relation becomes zero or more entity-to-entity edges (both
+// endpoints may tag more than one entity).
+func (a *App) kgGetEntityRelationEdges(limit int) []kgEntityRelEdge {
+	if !a.DB.HasTable("entry_relations") || !a.DB.HasTable("entity_metadata") {
+		return nil
+	}
+	sql := fmt.Sprintf(`SELECT from_type, from_id, to_type, to_id, relation FROM entry_relations LIMIT %d;`, limit)
+	out, err := a.DB.Run("-separator", "\t", sql)
+	if err != nil {
+		return nil
+	}
+	var edges []kgEntityRelEdge
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 5)
+		if len(parts) != 5 {
+			continue
+		}
+		fromEntities := a.kgEntitiesForSource(parts[0], parts[1])
+		toEntities := a.kgEntitiesForSource(parts[2], parts[3])
+		for _, fe := range fromEntities {
+			for _, te := range toEntities {
+				edges = append(edges, kgEntityRelEdge{fromEntity: fe, toEntity: te, relation: parts[4]})
+			}
+		}
+	}
+	return edges
+}
+
+func (a *App) kgEntitiesForSource(sourceType, sourceID string) []string {
+	sql := fmt.Sprintf(`SELECT entity FROM entity_metadata WHERE source_type='%s' AND source_id=%s;`, sourceType, sourceID)
+	out, err := a.DB.Run(sql)
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.TrimSpace(line) != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// kgEntityTypeIndex returns entity -> entity_type for coloring traversal
+// output, built once from kgGetTopEntities.
+func (a *App) kgEntityTypeIndex() map[string]string {
+	idx := map[string]string{}
+	for _, e := range a.kgGetTopEntities(5000) {
+		idx[e.entity] = e.eType
+	}
+	return idx
+}
+
+// ── BFS traversal ─────────────────────────────────────────────────────────
+
+type kgTraverseHop struct {
+	entity string
+	eType  string
+	via    string // entity that reached it
+	label  string // edge label
+}
+
+// kgTraverse performs a breadth-first walk over the union graph up to depth
+// hops, returning one layer of newly-discovered entities per hop. The
+// frontier is capped at kgFrontierCap entities to bound memory.
+func (a *App) kgTraverse(entityName string, depth int) ([][]kgTraverseHop, error) {
+	adj := a.kgBuildUnionAdjacency(2)
+	types := a.kgEntityTypeIndex()
+
+	visited := map[string]bool{entityName: true}
+	frontier := []string{entityName}
+	var layers [][]kgTraverseHop
+
+	for hop := 0; hop < depth && len(visited) < kgFrontierCap; hop++ {
+		var layer []kgTraverseHop
+		var next []string
+		for _, node := range frontier {
+			for _, edge := range adj[node] {
+				if visited[edge.to] {
+					continue
+				}
+				visited[edge.to] = true
+				layer = append(layer, kgTraverseHop{entity: edge.to, eType: types[edge.to], via: node, label: edge.label})
+				next = append(next, edge.to)
+				if len(visited) >= kgFrontierCap {
+					break
+				}
+			}
+			if len(visited) >= kgFrontierCap {
+				break
+			}
+		}
+		if len(layer) == 0 {
+			break
+		}
+		layers = append(layers, layer)
+		frontier = next
+	}
+	return layers, nil
+}
+
+func (a *App) kgRenderTraverse(entityName string, depth int) error {
+	rule(colored(fmt.Sprintf("Traverse: %s (depth %d)", entityName, depth), ansiMagenta))
+	layers, err := a.kgTraverse(entityName, depth)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		fmt.Println(dim("  No reachable entities found."))
+		return nil
+	}
+	for i, layer := range layers {
+		fmt.Printf("  %s\n", bold(fmt.Sprintf("Hop %d (%d entities)", i+1, len(layer))))
+		for _, h := range layer {
+			c := colorFor(h.eType)
+			fmt.Printf("  ├─ %s %s %s %s\n", colored(h.entity, c), dim("via"), h.via, dim("["+h.label+"]"))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// ── Shortest path (bidirectional BFS) ───────────────────────────────────
+
+const kgMaxPathHops = 6
+
+type kgPathStep struct {
+	entity string
+	label  string // edge label used to reach this entity from the previous one
+}
+
+// kgShortestPath runs bidirectional BFS over the union graph and returns the
+// path from `from` to `to`, or ok=false if none exists within kgMaxPathHops.
+func (a *App) kgShortestPath(from, to string) ([]kgPathStep, bool) {
+	if from == to {
+		return []kgPathStep{{entity: from}}, true
+	}
+	adj := a.kgBuildUnionAdjacency(2)
+
+	type frontierState struct {
+		parent map[string]kgPathStep // entity -> (predecessor label, predecessor entity recovered via map keys)
+	}
+	fwdParent := map[string]string{from: ""}
+	fwdLabel := map[string]string{}
+	bwdParent := map[string]string{to: ""}
+	bwdLabel := map[string]string{}
+	fwdFrontier := []string{from}
+	bwdFrontier := []string{to}
+
+	meet := ""
+	// Each round extends the forward side and then the backward side by one
+	// hop, so hop is the path length the two searches already cover.
+	for hop := 0; hop < kgMaxPathHops && meet == ""; hop += 2 {
+		var nextFwd []string
+		for _, node := range fwdFrontier {
+			for _, e := range adj[node] {
+				if _, ok := fwdParent[e.to]; ok {
+					continue
+				}
+				fwdParent[e.to] = node
+				fwdLabel[e.to] = e.label
+				nextFwd = append(nextFwd, e.to)
+				if _, ok := bwdParent[e.to]; ok {
+					meet = e.to
+					break
+				}
+			}
+			if meet != "" {
+				break
+			}
+		}
+		fwdFrontier = nextFwd
+		if meet != "" || len(fwdFrontier) == 0 || hop+1 >= kgMaxPathHops {
+			break
+		}
+
+		var nextBwd []string
+		for _, node := range bwdFrontier {
+			for _, e := range adj[node] {
+				if _, ok := bwdParent[e.to]; ok {
+					continue
+				}
+				bwdParent[e.to] = node
+				bwdLabel[e.to] = e.label
+				nextBwd = append(nextBwd, e.to)
+				if _, ok := fwdParent[e.to]; ok {
+					meet = e.to
+					break
+				}
+			}
+			if meet != "" {
+				break
+			}
+		}
+		bwdFrontier = nextBwd
+	}
+
+	if meet == "" {
+		return nil, false
+	}
+
+	var forwardHalf []kgPathStep
+	cur := meet
+	for cur != from {
+		forwardHalf = append([]kgPathStep{{entity: cur, label: fwdLabel[cur]}}, forwardHalf...)
+		cur = fwdParent[cur]
+	}
+	path := append([]kgPathStep{{entity: from}}, forwardHalf...)
+
+	cur = meet
+	for cur != to {
+		next := bwdParent[cur]
+		path = append(path, kgPathStep{entity: next, label: bwdLabel[cur]})
+		cur = next
+	}
+	return path, true
+}
+
+func (a *App) kgRenderShortestPath(from, to string) error {
+	rule(colored(fmt.Sprintf("Path: %s -> %s", from, to), ansiMagenta))
+	path, ok := a.kgShortestPath(from, to)
+	if !ok {
+		fmt.Println(dim(fmt.Sprintf("  No path within %d hops.", kgMaxPathHops)))
+		return nil
+	}
+	types := a.kgEntityTypeIndex()
+	for i, step := range path {
+		c := colorFor(types[step.entity])
+		if i == 0 {
+			fmt.Printf("  %s\n", colored(step.entity, c))
+			continue
+		}
+		fmt.Printf("  --[%s]--> %s\n", step.label, colored(step.entity, c))
+	}
+	return nil
+}
+
+// ── Centrality (weighted PageRank) ──────────────────────────────────────
+
+const (
+	kgPageRankDamping    = 0.85
+	kgPageRankIterations = 30
+)
+
+// kgCentrality computes weighted PageRank over the union graph (edge weight
+// = shared_sources for co-occurrences, 1.0 for explicit relations) with a
+// uniform initial vector, returning entities sorted by descending score.
+func (a *App) kgCentrality() []kgEntity {
+	adj := a.kgBuildUnionAdjacency(1)
+	types := a.kgEntityTypeIndex()
+
+	nodes := map[string]bool{}
+	for from, edges := range adj {
+		nodes[from] = true
+		for _, e := range edges {
+			nodes[e.to] = true
+		}
+	}
+	n := len(nodes)
+	if n == 0 {
+		return nil
+	}
+
+	outWeight := map[string]float64{}
+	for from, edges := range adj {
+		for _, e := range edges {
+			outWeight[from] += e.weight
+		}
+	}
+
+	rank := map[string]float64{}
+	for node := range nodes {
+		rank[node] = 1.0 / float64(n)
+	}
+
+	for iter := 0; iter < kgPageRankIterations; iter++ {
+		next := map[string]float64{}
+		base := (1 - kgPageRankDamping) / float64(n)
+		for node := range nodes {
+			next[node] = base
+		}
+		for from, edges := range adj {
+			if outWeight[from] == 0 {
+				continue
+			}
+			for _, e := range edges {
+				next[e.to] += kgPageRankDamping * rank[from] * (e.weight / outWeight[from])
+			}
+		}
+		rank = next
+	}
+
+	result := make([]kgEntity, 0, n)
+	for node := range nodes {
+		result = append(result, kgEntity{entity: node, eType: types[node], refCount: int(rank[node] * 1000000)})
+	}
+	for i := 0; i < len(result); i++ {
+		for j := i + 1; j < len(result); j++ {
+			if result[j].refCount > result[i].refCount {
+				result[i], result[j] = result[j], result[i]
+			}
+		}
+	}
+	return result
+}
+
+func (a *App) kgRenderCentrality() error {
+	rule(colored("Centrality (PageRank)", ansiMagenta))
+	ranked := a.kgCentrality()
+	if len(ranked) == 0 {
+		fmt.Println(dim("  Not enough graph data to compute centrality."))
+		return nil
+	}
+	showCount := len(ranked)
+	if showCount > 20 {
+		showCount = 20
+	}
+	for _, e := range ranked[:showCount] {
+		c := colorFor(e.eType)
+		fmt.Printf("  %s %s\n", colored(padRight(e.entity, 30), c), dim(fmt.Sprintf("score=%s", strconv.FormatFloat(float64(e.refCount)/1000000, 'f', 6, 64))))
+	}
+	return nil
+}
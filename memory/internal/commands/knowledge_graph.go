@@ -2,10 +2,10 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"claude-turbo-search/memorydb/internal/db"
+	"claude-turbo-search/memorydb/internal/models"
 )
 
 // ANSI color codes
@@ -54,8 +54,10 @@ func padRight(s string, width int) string {
 	return s + strings.Repeat(" ", width-len(s))
 }
 
-// CmdKnowledgeGraph dispatches to the correct view.
-func (a *App) CmdKnowledgeGraph(view, entity string) error {
+// CmdKnowledgeGraph dispatches to the correct view. extra carries
+// view-specific arguments: --depth for "explore", the second entity for
+// "path", and the flags of "export" and "gc".
+func (a *App) CmdKnowledgeGraph(view, entity string, extra ...string) error {
 	if !a.dbExists() {
 		fmt.Println("No memory database found. Run /turbo-index or /remember first.")
 		return nil
@@ -72,11 +74,35 @@ func (a *App) CmdKnowledgeGraph(view, entity string) error {
 		if entity == "" {
 			return fmt.Errorf("explore command requires an entity name")
 		}
+		depth := kgExtractDepthFlag(extra)
+		if depth > 0 {
+			return a.kgRenderTraverse(entity, depth)
+		}
 		return a.kgRenderExplore(entity)
+	case "path":
+		if entity == "" || len(extra) == 0 {
+			return fmt.Errorf("path command requires two entity names: path <a> <b>")
+		}
+		return a.kgRenderShortestPath(entity, extra[0])
+	case "centrality":
+		return a.kgRenderCentrality()
 	case "full":
 		return a.kgRenderFull()
+	case "query":
+		if entity == "" {
+			return a.kgQueryREPL()
+		}
+		return a.kgRunQuery(entity)
+	case "export":
+		return a.kgExport(extra)
+	case "gc":
+		policy, err := kgParseGCPolicy(extra)
+		if err != nil {
+			return err
+		}
+		return a.CmdKnowledgeGraphGC(policy)
 	default:
-		return fmt.Errorf("unknown knowledge-graph view: %s (use: full, stats, graph, timeline, explore)", view)
+		return fmt.Errorf("unknown knowledge-graph view: %s (use: full, stats, graph, timeline, explore, query, export, path, centrality, gc)", view)
 	}
 }
 
@@ -145,27 +171,18 @@ func (a *App) kgGetEntities(limit int) map[string][]kgEntity {
 	if !a.DB.HasTable("entity_metadata") {
 		return nil
 	}
-	sql := fmt.Sprintf(`SELECT entity, entity_type, COUNT(*) as ref_count
+	rows, err := db.Scan[models.EntityRefCountRow](a.DB, `SELECT entity, entity_type, COUNT(*) as ref_count
 FROM entity_metadata
 GROUP BY entity, entity_type
 ORDER BY ref_count DESC
-LIMIT %d;`, limit)
-	out, err := a.DB.Run("-separator", "\t", sql)
+LIMIT ?;`, limit)
 	if err != nil {
 		return nil
 	}
 	grouped := map[string][]kgEntity{}
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) != 3 {
-			continue
-		}
-		refs, _ := strconv.Atoi(parts[2])
-		grouped[parts[1]] = append(grouped[parts[1]], kgEntity{
-			entity: parts[0], eType: parts[1], refCount: refs,
+	for _, r := range rows {
+		grouped[r.EntityType] = append(grouped[r.EntityType], kgEntity{
+			entity: r.Entity, eType: r.EntityType, refCount: r.RefCount,
 		})
 	}
 	return grouped
@@ -175,26 +192,17 @@ func (a *App) kgGetTopEntities(limit int) []kgEntity {
 	if !a.DB.HasTable("entity_metadata") {
 		return nil
 	}
-	sql := fmt.Sprintf(`SELECT entity, entity_type, COUNT(*) as ref_count
+	rows, err := db.Scan[models.EntityRefCountRow](a.DB, `SELECT entity, entity_type, COUNT(*) as ref_count
 FROM entity_metadata
 GROUP BY entity, entity_type
 ORDER BY ref_count DESC
-LIMIT %d;`, limit)
-	out, err := a.DB.Run("-separator", "\t", sql)
+LIMIT ?;`, limit)
 	if err != nil {
 		return nil
 	}
 	var result []kgEntity
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) != 3 {
-			continue
-		}
-		refs, _ := strconv.Atoi(parts[2])
-		result = append(result, kgEntity{entity: parts[0], eType: parts[1], refCount: refs})
+	for _, r := range rows {
+		result = append(result, kgEntity{entity: r.Entity, eType: r.EntityType, refCount: r.RefCount})
 	}
 	return result
 }
@@ -203,49 +211,30 @@ func (a *App) kgGetRelations() []kgRelation {
 	if !a.DB.HasTable("entry_relations") {
 		return nil
 	}
-	sql := `SELECT
+	rows, err := db.Scan[models.EntryRelationRow](a.DB, `SELECT
     er.from_type, er.from_id, er.to_type, er.to_id, er.relation,
-    CASE er.from_type
+    COALESCE(CASE er.from_type
         WHEN 'session'   THEN (SELECT SUBSTR(summary, 1, 60) FROM sessions WHERE id = er.from_id)
         WHEN 'knowledge' THEN (SELECT area FROM knowledge WHERE id = er.from_id)
         WHEN 'fact'      THEN (SELECT SUBSTR(fact, 1, 60) FROM facts WHERE id = er.from_id)
-    END,
-    CASE er.to_type
+    END, '') as from_label,
+    COALESCE(CASE er.to_type
         WHEN 'session'   THEN (SELECT SUBSTR(summary, 1, 60) FROM sessions WHERE id = er.to_id)
         WHEN 'knowledge' THEN (SELECT area FROM knowledge WHERE id = er.to_id)
         WHEN 'fact'      THEN (SELECT SUBSTR(fact, 1, 60) FROM facts WHERE id = er.to_id)
-    END
+    END, '') as to_label
 FROM entry_relations er
 ORDER BY er.created_at DESC
-LIMIT 100;`
-	out, err := a.DB.Run("-separator", "\t", sql)
+LIMIT 100;`)
 	if err != nil {
 		return nil
 	}
-	var result []kgRelation
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 7)
-		if len(parts) < 5 {
-			continue
-		}
-		fromID, _ := strconv.Atoi(parts[1])
-		toID, _ := strconv.Atoi(parts[3])
-		fromLabel := ""
-		toLabel := ""
-		if len(parts) > 5 {
-			fromLabel = parts[5]
-		}
-		if len(parts) > 6 {
-			toLabel = parts[6]
-		}
+	result := make([]kgRelation, 0, len(rows))
+	for _, r := range rows {
 		result = append(result, kgRelation{
-			fromType: parts[0], fromID: fromID,
-			toType: parts[2], toID: toID,
-			relation: parts[4],
-			fromLabel: fromLabel, toLabel: toLabel,
+			fromType: r.FromType, fromID: r.FromID,
+			toType: r.ToType, toID: r.ToID,
+			relation: r.Relation, fromLabel: r.FromLabel, toLabel: r.ToLabel,
 		})
 	}
 	return result
@@ -255,9 +244,9 @@ func (a *App) kgGetCoOccurrences(limit int) []kgCoOccurrence {
 	if !a.DB.HasTable("entity_metadata") {
 		return nil
 	}
-	sql := fmt.Sprintf(`SELECT
-    a.entity, a.entity_type,
-    b.entity, b.entity_type,
+	rows, err := db.Scan[models.CoOccurrenceRow](a.DB, `SELECT
+    a.entity as entity_a, a.entity_type as type_a,
+    b.entity as entity_b, b.entity_type as type_b,
     COUNT(*) as shared_sources
 FROM entity_metadata a
 JOIN entity_metadata b
@@ -267,25 +256,16 @@ JOIN entity_metadata b
 GROUP BY a.entity, b.entity
 HAVING shared_sources >= 2
 ORDER BY shared_sources DESC
-LIMIT %d;`, limit)
-	out, err := a.DB.Run("-separator", "\t", sql)
+LIMIT ?;`, limit)
 	if err != nil {
 		return nil
 	}
-	var result []kgCoOccurrence
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 5)
-		if len(parts) != 5 {
-			continue
-		}
-		shared, _ := strconv.Atoi(parts[4])
+	result := make([]kgCoOccurrence, 0, len(rows))
+	for _, r := range rows {
 		result = append(result, kgCoOccurrence{
-			entityA: parts[0], typeA: parts[1],
-			entityB: parts[2], typeB: parts[3],
-			sharedSources: shared,
+			entityA: r.EntityA, typeA: r.TypeA,
+			entityB: r.EntityB, typeB: r.TypeB,
+			sharedSources: r.SharedSources,
 		})
 	}
 	return result
@@ -295,34 +275,18 @@ func (a *App) kgGetTimeline(limit int) []kgTimelineEntry {
 	var entries []kgTimelineEntry
 
 	if a.DB.HasTable("sessions") {
-		sql := fmt.Sprintf(`SELECT id, created_at, summary, 'session' FROM sessions ORDER BY created_at DESC LIMIT %d;`, limit)
-		out, _ := a.DB.Run("-separator", "\t", sql)
-		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "\t", 4)
-			if len(parts) != 4 {
-				continue
-			}
-			id, _ := strconv.Atoi(parts[0])
-			entries = append(entries, kgTimelineEntry{id: id, createdAt: parts[1], summary: parts[2], entryType: parts[3]})
+		rows, _ := db.Scan[models.TimelineEntryRow](a.DB,
+			`SELECT id, created_at, summary, 'session' as entry_type FROM sessions ORDER BY created_at DESC LIMIT ?;`, limit)
+		for _, r := range rows {
+			entries = append(entries, kgTimelineEntry{id: r.ID, createdAt: r.CreatedAt, summary: r.Summary, entryType: r.EntryType})
 		}
 	}
 
 	if a.DB.HasTable("knowledge") {
-		sql := fmt.Sprintf(`SELECT id, updated_at, area || ': ' || summary, 'knowledge' FROM knowledge ORDER BY updated_at DESC LIMIT %d;`, limit)
-		out, _ := a.DB.Run("-separator", "\t", sql)
-		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "\t", 4)
-			if len(parts) != 4 {
-				continue
-			}
-			id, _ := strconv.Atoi(parts[0])
-			entries = append(entries, kgTimelineEntry{id: id, createdAt: parts[1], summary: parts[2], entryType: parts[3]})
+		rows, _ := db.Scan[models.TimelineEntryRow](a.DB,
+			`SELECT id, updated_at as created_at, area || ': ' || summary as summary, 'knowledge' as entry_type FROM knowledge ORDER BY updated_at DESC LIMIT ?;`, limit)
+		for _, r := range rows {
+			entries = append(entries, kgTimelineEntry{id: r.ID, createdAt: r.CreatedAt, summary: r.Summary, entryType: r.EntryType})
 		}
 	}
 
@@ -347,32 +311,19 @@ func (a *App) kgGetEntityDetail(name string) kgEntityDetail {
 	}
 
 	// Sources
-	sql := fmt.Sprintf(`SELECT em.entity_type, em.source_type, em.source_id,
-    CASE em.source_type
+	sourceRows, err := db.Scan[models.EntitySourceRow](a.DB, `SELECT em.entity_type, em.source_type, em.source_id,
+    COALESCE(CASE em.source_type
         WHEN 'session'   THEN (SELECT SUBSTR(summary, 1, 80) FROM sessions WHERE id = em.source_id)
         WHEN 'knowledge' THEN (SELECT area || ': ' || SUBSTR(summary, 1, 60) FROM knowledge WHERE id = em.source_id)
         WHEN 'fact'      THEN (SELECT SUBSTR(fact, 1, 80) FROM facts WHERE id = em.source_id)
-    END
+    END, '') as context
 FROM entity_metadata em
-WHERE em.entity LIKE '%%%s%%'
-ORDER BY em.created_at DESC;`, db.SQLQuote(name))
-	out, err := a.DB.Run("-separator", "\t", sql)
+WHERE em.entity LIKE '%' || ? || '%'
+ORDER BY em.created_at DESC;`, name)
 	if err == nil {
-		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "\t", 4)
-			if len(parts) < 3 {
-				continue
-			}
-			sid, _ := strconv.Atoi(parts[2])
-			ctx := ""
-			if len(parts) > 3 {
-				ctx = parts[3]
-			}
+		for _, r := range sourceRows {
 			detail.sources = append(detail.sources, kgSource{
-				entityType: parts[0], sourceType: parts[1], sourceID: sid, context: ctx,
+				entityType: r.EntityType, sourceType: r.SourceType, sourceID: r.SourceID, context: r.Context,
 			})
 		}
 	}
@@ -380,59 +331,40 @@ ORDER BY em.created_at DESC;`, db.SQLQuote(name))
 	// Relations involving this entity's sources
 	if a.DB.HasTable("entry_relations") && len(detail.sources) > 0 {
 		var conds []string
+		var args []interface{}
 		for _, s := range detail.sources {
-			conds = append(conds,
-				fmt.Sprintf("(from_type='%s' AND from_id=%d)", db.SQLQuote(s.sourceType), s.sourceID),
-				fmt.Sprintf("(to_type='%s' AND to_id=%d)", db.SQLQuote(s.sourceType), s.sourceID),
-			)
+			conds = append(conds, "(from_type=? AND from_id=?)", "(to_type=? AND to_id=?)")
+			args = append(args, s.sourceType, s.sourceID, s.sourceType, s.sourceID)
 		}
 		relSQL := fmt.Sprintf(`SELECT from_type, from_id, to_type, to_id, relation
 FROM entry_relations WHERE %s LIMIT 20;`, strings.Join(conds, " OR "))
-		out, err := a.DB.Run("-separator", "\t", relSQL)
+		relRows, err := db.Scan[models.RelationRow](a.DB, relSQL, args...)
 		if err == nil {
-			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
-				parts := strings.SplitN(line, "\t", 5)
-				if len(parts) != 5 {
-					continue
-				}
-				fid, _ := strconv.Atoi(parts[1])
-				tid, _ := strconv.Atoi(parts[3])
+			for _, r := range relRows {
 				detail.relations = append(detail.relations, kgRelation{
-					fromType: parts[0], fromID: fid,
-					toType: parts[2], toID: tid,
-					relation: parts[4],
+					fromType: r.FromType, fromID: r.FromID,
+					toType: r.ToType, toID: r.ToID,
+					relation: r.Relation,
 				})
 			}
 		}
 	}
 
 	// Co-occurring entities
-	coSQL := fmt.Sprintf(`SELECT DISTINCT b.entity, b.entity_type, COUNT(*) as shared
+	coRows, err := db.Scan[models.CoOccurrenceSummaryRow](a.DB, `SELECT DISTINCT b.entity, b.entity_type, COUNT(*) as shared
 FROM entity_metadata a
 JOIN entity_metadata b
     ON a.source_type = b.source_type
     AND a.source_id = b.source_id
     AND a.entity != b.entity
-WHERE a.entity LIKE '%%%s%%'
+WHERE a.entity LIKE '%' || ? || '%'
 GROUP BY b.entity, b.entity_type
 ORDER BY shared DESC
-LIMIT 20;`, db.SQLQuote(name))
-	out, err = a.DB.Run("-separator", "\t", coSQL)
+LIMIT 20;`, name)
 	if err == nil {
-		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "\t", 3)
-			if len(parts) != 3 {
-				continue
-			}
-			shared, _ := strconv.Atoi(parts[2])
+		for _, r := range coRows {
 			detail.coOccurring = append(detail.coOccurring, kgEntity{
-				entity: parts[0], eType: parts[1], refCount: shared,
+				entity: r.Entity, eType: r.EntityType, refCount: r.Shared,
 			})
 		}
 	}
@@ -499,6 +431,21 @@ func (a *App) kgRenderStats() error {
 		}
 	}
 
+	// Most central entities (weighted PageRank over the union graph)
+	ranked := a.kgCentrality()
+	if len(ranked) > 0 {
+		fmt.Println()
+		fmt.Println(bold("  Most Central Entities"))
+		showCount := len(ranked)
+		if showCount > 10 {
+			showCount = 10
+		}
+		for _, e := range ranked[:showCount] {
+			c := colorFor(e.eType)
+			fmt.Printf("  %s %s\n", colored(padRight(e.entity, 30), c), dim(fmt.Sprintf("score=%.6f", float64(e.refCount)/1000000)))
+		}
+	}
+
 	return nil
 }
 
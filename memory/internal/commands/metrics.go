@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+
+	"claude-turbo-search/memorydb/internal/metrics"
+)
+
+// CmdServeMetrics starts a blocking HTTP server on addr exposing /metrics in
+// Prometheus text format: the op/table/status counters and histograms
+// a.DB's Metrics registry has accumulated from Run/RunSQL/Exec calls, plus
+// the same tracked_sessions/total_tokens_used/total_tokens_saved aggregates
+// CmdTokenStats prints, published as gauges so a long-running session can be
+// scraped the way OPA exposes per-storage-operation metrics.
+func (a *App) CmdServeMetrics(addr string) error {
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		t, err := a.tokenMetricsAggregates()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, a.Metrics.Render(metricsGauges(t)))
+	})
+	fmt.Printf("Serving Prometheus metrics on %s/metrics\n", addr)
+	return http.ListenAndServe(addr, nil)
+}
+
+// metricsGauges converts a tokenMetricsTotals into the Gauges CmdServeMetrics
+// renders.
+func metricsGauges(t tokenMetricsTotals) metrics.Gauges {
+	return metrics.Gauges{
+		TrackedSessions:  int64(t.tracked),
+		TotalTokensUsed:  t.tokensUsed,
+		TotalTokensSaved: t.tokensSaved,
+	}
+}
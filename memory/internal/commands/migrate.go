@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"claude-turbo-search/memorydb/internal/migrations"
+)
+
+// CmdMigrate implements `memorydb migrate [up|down|status|to <n>]`.
+func (a *App) CmdMigrate(args []string) error {
+	if err := a.ensureDir(); err != nil {
+		return err
+	}
+	conn, err := a.DB.DB()
+	if err != nil {
+		return err
+	}
+	m := migrations.New(conn)
+
+	sub := arg(args, 0)
+	switch sub {
+	case "", "up":
+		if err := m.Up(0); err != nil {
+			return err
+		}
+		fmt.Println("Migrated to latest.")
+		return nil
+	case "down":
+		count := parseIntOrDefault(arg(args, 1), 1)
+		if err := m.Down(count); err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s).\n", count)
+		return nil
+	case "to":
+		target, err := strconv.Atoi(arg(args, 1))
+		if err != nil {
+			return fmt.Errorf("memorydb migrate to <version>: %w", err)
+		}
+		if err := m.To(target); err != nil {
+			return err
+		}
+		fmt.Printf("Migrated to version %d.\n", target)
+		return nil
+	case "status":
+		report, err := m.StatusReport()
+		if err != nil {
+			return err
+		}
+		for _, s := range report {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q (use: up, down, status, to)", sub)
+	}
+}
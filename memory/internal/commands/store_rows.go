@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"claude-turbo-search/memorydb/internal/store"
+)
+
+// rowInt reads col from r as an int64, regardless of which concrete numeric
+// (or numeric-as-text) type the backend behind a.Store handed back for it:
+// database/sql commonly yields int64, the badger backend's JSON-decoded
+// rows yield float64, and anything read from a TEXT column yields string.
+func rowInt(r store.Row, col string) int64 {
+	switch v := r[col].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case []byte:
+		return int64(parseIntOrDefault(string(v), 0))
+	case string:
+		return int64(parseIntOrDefault(v, 0))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"claude-turbo-search/memorydb/internal/vector"
+)
+
+// encodedField is what CmdAddSession/CmdAddKnowledge/CmdAddFact write for a
+// summary/patterns/fact value: plain holds the original text for rows
+// under vector.TextCompressionThreshold (encoding "raw"), or is left empty
+// with the Snappy-compressed bytes in compressed (encoding "snappy") once a
+// row is long enough to be worth it. Only the hot read paths that feed
+// vsearch (bruteForceVSearch, fetchEmbeddingRow) and the embedding queue
+// decode the compressed form back; other report commands (CmdRecent,
+// CmdContext, the knowledge-graph exporters) still read the plain column
+// directly and will see it blank for compressed rows — same incremental
+// migration tradeoff db.Client.Run's callers already live with.
+type encodedField struct {
+	plain      string
+	encoding   string
+	compressed []byte
+}
+
+func encodeField(text string) encodedField {
+	data, compressed := vector.CompressText(text)
+	if !compressed {
+		return encodedField{plain: text, encoding: "raw"}
+	}
+	return encodedField{encoding: "snappy", compressed: data}
+}
+
+// decodeField reverses encodeField given a row's plain column, its
+// `<col>_encoding` value, and hex(`<col>_compressed`) from the same SELECT.
+func decodeField(plain, encoding, hexCompressed string) (string, error) {
+	if encoding != "snappy" {
+		return plain, nil
+	}
+	raw, err := hex.DecodeString(hexCompressed)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex compressed field: %w", err)
+	}
+	return vector.DecompressText(raw)
+}
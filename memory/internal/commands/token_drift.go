@@ -0,0 +1,42 @@
+package commands
+
+import "fmt"
+
+// CmdTokenDrift compares estimated_tokens_used against measured_tokens_used
+// for every token_metrics row that has a real measurement (estimate-mode
+// rows leave measured_tokens_used at 0 and are excluded), reporting how far
+// the old per-search/per-file heuristic drifts from actual token counts.
+func (a *App) CmdTokenDrift() error {
+	empty := `{"measured_sessions":0,"avg_drift_pct":0}`
+	if !a.dbExists() {
+		fmt.Print(empty)
+		fmt.Println()
+		return nil
+	}
+	a.ensureTokenMetricsTable()
+
+	rows, err := a.Store.Query("token_metrics",
+		[]string{"session_id", "estimated_tokens_used", "measured_tokens_used"},
+		"measured_tokens_used > 0")
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		fmt.Print(empty)
+		fmt.Println()
+		return nil
+	}
+
+	var totalDriftPct float64
+	fmt.Println("session_id|estimated|measured|drift_pct")
+	for _, r := range rows {
+		estimated := rowInt(r, "estimated_tokens_used")
+		measured := rowInt(r, "measured_tokens_used")
+		driftPct := float64(estimated-measured) / float64(measured) * 100
+		totalDriftPct += driftPct
+		fmt.Printf("%d|%d|%d|%.1f\n", rowInt(r, "session_id"), estimated, measured, driftPct)
+	}
+	fmt.Printf(`{"measured_sessions":%d,"avg_drift_pct":%.1f}`, len(rows), totalDriftPct/float64(len(rows)))
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"claude-turbo-search/memorydb/internal/db"
+	"claude-turbo-search/memorydb/internal/models"
+	"claude-turbo-search/memorydb/internal/vector"
+)
+
+func vectorIndexKey(sourceType string, sourceID int) string {
+	return fmt.Sprintf("%s:%d", sourceType, sourceID)
+}
+
+func splitVectorIndexKey(id string) (sourceType string, sourceID int, ok bool) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+	sourceID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[0], sourceID, true
+}
+
+// loadVectorIndex rebuilds an in-memory HNSW graph from the persisted
+// vector_index table. ok is false when the table doesn't exist yet (old
+// DBs created before this index existed), so CmdVSearch can fall back to
+// its brute-force scan.
+func (a *App) loadVectorIndex() (idx *vector.HNSW, ok bool) {
+	if !a.DB.HasTable("vector_index") {
+		return nil, false
+	}
+	idx = vector.NewHNSW(16, 200, 64)
+	rows, _ := db.Scan[models.VectorIndexNodeRow](a.DB, "SELECT id, hex(node) AS node_hex FROM vector_index ORDER BY id;")
+	for _, r := range rows {
+		raw, err := hex.DecodeString(r.NodeHex)
+		if err != nil {
+			continue
+		}
+		_ = idx.LoadNode(raw)
+	}
+	return idx, true
+}
+
+// indexEmbedding adds vec to idx and persists the resulting node into
+// vector_index, keeping the in-memory graph and its on-disk copy in sync.
+func (a *App) indexEmbedding(idx *vector.HNSW, sourceType string, sourceID int, vec []float64) error {
+	id := vectorIndexKey(sourceType, sourceID)
+	if err := idx.Add(id, vec); err != nil {
+		return err
+	}
+	node, ok := idx.MarshalNode(id)
+	if !ok {
+		return fmt.Errorf("failed to marshal vector index node %s", id)
+	}
+	_, err := a.DB.Exec(
+		"INSERT OR REPLACE INTO vector_index (id, source_type, source_id, node) VALUES (?, ?, ?, ?);",
+		id, sourceType, sourceID, node,
+	)
+	return err
+}
+
+// syncVectorIndex adds any embeddings present in sessions/knowledge/facts
+// but missing from vector_index, without touching rows already indexed.
+// CmdEmbed calls this after a batch run so freshly generated embeddings
+// join the ANN graph incrementally instead of needing a full CmdReindex,
+// and CmdVSearch calls it before querying to catch anything embedded
+// since the last sync.
+func (a *App) syncVectorIndex(idx *vector.HNSW) (int, error) {
+	sources := []struct{ sourceType, table string }{
+		{"session", "sessions"},
+		{"knowledge", "knowledge"},
+		{"fact", "facts"},
+	}
+	synced := 0
+	for _, s := range sources {
+		query := fmt.Sprintf(
+			"SELECT id, hex(embedding) AS embedding_hex FROM %s WHERE embedding IS NOT NULL AND id NOT IN (SELECT source_id FROM vector_index WHERE source_type = ?);",
+			s.table)
+		rows, err := db.Scan[models.EmbeddingIDRow](a.DB, query, s.sourceType)
+		if err != nil {
+			return synced, err
+		}
+		for _, r := range rows {
+			vec, err := vector.HexBlobToFloat64(r.EmbeddingHex)
+			if err != nil {
+				continue
+			}
+			if err := a.indexEmbedding(idx, s.sourceType, r.ID, vec); err != nil {
+				return synced, err
+			}
+			synced++
+		}
+	}
+	return synced, nil
+}
+
+// vsearchViaIndex queries idx for ANN candidates and re-fetches each one's
+// stored embedding for an exact cosine score, rather than trusting the
+// index's own (already-cosine) distance — the index is sublinear, the
+// re-rank is cheap since it only touches the top candidates.
+func (a *App) vsearchViaIndex(idx *vector.HNSW, queryVec []float64, limit int) []scoredResult {
+	candidates, _ := idx.Search(queryVec, limit*4+10)
+	results := make([]scoredResult, 0, len(candidates))
+	for _, c := range candidates {
+		sourceType, sourceID, ok := splitVectorIndexKey(c.ID)
+		if !ok {
+			continue
+		}
+		content, emb, ok := a.fetchEmbeddingRow(sourceType, sourceID)
+		if !ok {
+			continue
+		}
+		results = append(results, scoredResult{
+			sourceType: sourceType,
+			sourceID:   sourceID,
+			content:    content,
+			similarity: vector.CosineSimilarity(queryVec, emb),
+		})
+	}
+	return results
+}
+
+// fetchEmbeddingRow re-reads one row's display content and embedding by id,
+// for the exact re-rank step in vsearchViaIndex. The summary/fact text is
+// decoded through decodeField since it may be Snappy-compressed (see
+// text_compression.go).
+func (a *App) fetchEmbeddingRow(sourceType string, sourceID int) (content string, emb []float64, ok bool) {
+	switch sourceType {
+	case "session":
+		rows, err := db.Scan[models.EmbeddedSessionRow](a.DB, `SELECT id, summary, summary_encoding,
+    hex(summary_compressed) AS summary_compressed_hex, hex(embedding) AS embedding_hex
+FROM sessions WHERE id = ?;`, sourceID)
+		if err != nil || len(rows) == 0 {
+			return "", nil, false
+		}
+		content, err = decodeField(rows[0].Summary, rows[0].SummaryEncoding, rows[0].SummaryCompressed)
+		if err != nil {
+			return "", nil, false
+		}
+		emb, err = vector.HexBlobToFloat64(rows[0].Embedding)
+		if err != nil {
+			return "", nil, false
+		}
+		return content, emb, true
+	case "knowledge":
+		rows, err := db.Scan[models.KnowledgeRow](a.DB, `SELECT id, area, summary, summary_encoding,
+    hex(summary_compressed) AS summary_compressed_hex, hex(embedding) AS embedding_hex
+FROM knowledge WHERE id = ?;`, sourceID)
+		if err != nil || len(rows) == 0 {
+			return "", nil, false
+		}
+		summary, err := decodeField(rows[0].Summary, rows[0].SummaryEncoding, rows[0].SummaryCompressed)
+		if err != nil {
+			return "", nil, false
+		}
+		content = fmt.Sprintf("%s: %s", rows[0].Area, summary)
+		emb, err = vector.HexBlobToFloat64(rows[0].Embedding)
+		if err != nil {
+			return "", nil, false
+		}
+		return content, emb, true
+	case "fact":
+		rows, err := db.Scan[models.FactRow](a.DB, `SELECT id, fact, fact_encoding,
+    hex(fact_compressed) AS fact_compressed_hex, hex(embedding) AS embedding_hex
+FROM facts WHERE id = ?;`, sourceID)
+		if err != nil || len(rows) == 0 {
+			return "", nil, false
+		}
+		content, err = decodeField(rows[0].Fact, rows[0].Encoding, rows[0].Compressed)
+		if err != nil {
+			return "", nil, false
+		}
+		emb, err = vector.HexBlobToFloat64(rows[0].Embedding)
+		if err != nil {
+			return "", nil, false
+		}
+		return content, emb, true
+	default:
+		return "", nil, false
+	}
+}
+
+// CmdReindex rebuilds vector_index from scratch by replaying every stored
+// embedding through a fresh HNSW build. Run it after a bulk embedding
+// backfill, or if ANN results look stale.
+func (a *App) CmdReindex() error {
+	if !a.dbExists() {
+		return errors.New("no memory database found. run 'memory-db.sh init' first")
+	}
+	if err := a.EnsureSchema(); err != nil {
+		return err
+	}
+	if _, err := a.DB.Exec("DELETE FROM vector_index;"); err != nil {
+		return err
+	}
+
+	idx := vector.NewHNSW(16, 200, 64)
+	total := 0
+	sources := []struct {
+		sourceType, idCol, embCol, table string
+	}{
+		{"session", "id", "embedding", "sessions"},
+		{"knowledge", "id", "embedding", "knowledge"},
+		{"fact", "id", "embedding", "facts"},
+	}
+	for _, s := range sources {
+		query := fmt.Sprintf("SELECT %s AS id, hex(%s) AS embedding_hex FROM %s WHERE %s IS NOT NULL;",
+			s.idCol, s.embCol, s.table, s.embCol)
+		rows, err := db.Scan[models.EmbeddingIDRow](a.DB, query)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			vec, err := vector.HexBlobToFloat64(r.EmbeddingHex)
+			if err != nil {
+				continue
+			}
+			if err := a.indexEmbedding(idx, s.sourceType, r.ID, vec); err != nil {
+				return err
+			}
+			total++
+		}
+	}
+
+	fmt.Printf("Reindexed %d embedding(s) into vector_index.\n", total)
+	return nil
+}
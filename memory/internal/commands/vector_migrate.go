@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"claude-turbo-search/memorydb/internal/db"
+	"claude-turbo-search/memorydb/internal/models"
+	"claude-turbo-search/memorydb/internal/vector"
+)
+
+// CmdMigrateVectors re-encodes rows written before quantization/compression
+// existed: embeddings still stored as a raw float64 blob are quantized to
+// int8 in place, and summary/patterns/fact values still stored raw that are
+// now long enough to clear vector.TextCompressionThreshold get
+// Snappy-compressed. Already-migrated rows are left untouched, so this is
+// safe to run repeatedly (e.g. after a bulk import).
+func (a *App) CmdMigrateVectors() error {
+	if !a.dbExists() {
+		return errors.New("no memory database found. run 'memory-db.sh init' first")
+	}
+	embeddings, err := a.migrateEmbeddings()
+	if err != nil {
+		return err
+	}
+	text, err := a.migrateTextFields()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Quantized %d embedding(s), compressed %d text field(s).\n", embeddings, text)
+	if embeddings > 0 {
+		fmt.Println("Run 'memorydb reindex' to rebuild vector_index from the re-encoded embeddings.")
+	}
+	return nil
+}
+
+func (a *App) migrateEmbeddings() (int, error) {
+	total := 0
+	for _, table := range []string{"sessions", "knowledge", "facts"} {
+		rows, err := db.Scan[models.EmbeddingIDRow](a.DB, fmt.Sprintf(
+			"SELECT id, hex(embedding) AS embedding_hex FROM %s WHERE embedding IS NOT NULL;", table))
+		if err != nil {
+			return total, err
+		}
+		for _, r := range rows {
+			raw, err := hex.DecodeString(r.EmbeddingHex)
+			if err != nil || vector.IsQuantized(raw) {
+				continue
+			}
+			vec, err := vector.HexBlobToFloat64(r.EmbeddingHex)
+			if err != nil {
+				continue
+			}
+			if _, err := a.DB.Exec(fmt.Sprintf("UPDATE %s SET embedding = ? WHERE id = ?;", table),
+				vector.QuantizeVector(vec), r.ID); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	return total, nil
+}
+
+// textColumn names the plain/encoding/compressed column triple for one
+// compressible field.
+type textColumn struct {
+	table, idCol, plainCol, encodingCol, compressedCol string
+}
+
+func (a *App) migrateTextFields() (int, error) {
+	columns := []textColumn{
+		{"sessions", "id", "summary", "summary_encoding", "summary_compressed"},
+		{"knowledge", "id", "summary", "summary_encoding", "summary_compressed"},
+		{"knowledge", "id", "patterns", "patterns_encoding", "patterns_compressed"},
+		{"facts", "id", "fact", "fact_encoding", "fact_compressed"},
+	}
+	total := 0
+	for _, c := range columns {
+		rows, err := db.Scan[models.IDValueRow](a.DB, fmt.Sprintf(
+			"SELECT %s, %s AS value FROM %s WHERE %s = 'raw';", c.idCol, c.plainCol, c.table, c.encodingCol))
+		if err != nil {
+			return total, err
+		}
+		for _, r := range rows {
+			data, compressed := vector.CompressText(r.Value)
+			if !compressed {
+				continue
+			}
+			_, err = a.DB.Exec(fmt.Sprintf(
+				"UPDATE %s SET %s = '', %s = 'snappy', %s = ? WHERE %s = ?;",
+				c.table, c.plainCol, c.encodingCol, c.compressedCol, c.idCol,
+			), data, r.ID)
+			if err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	return total, nil
+}
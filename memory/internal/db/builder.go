@@ -0,0 +1,281 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cond renders itself to a parameterized SQL predicate plus its bind args,
+// in the style of xorm's builder.Cond. Eq, In and And/Or below are the
+// handful of shapes the commands package actually needs.
+type Cond interface {
+	ToSQL() (string, []interface{})
+}
+
+// Eq builds `col = ?` predicates ANDed together, e.g. Eq{"entity_type": "file"}.
+type Eq map[string]interface{}
+
+func (e Eq) ToSQL() (string, []interface{}) {
+	if len(e) == 0 {
+		return "1=1", nil
+	}
+	parts := make([]string, 0, len(e))
+	args := make([]interface{}, 0, len(e))
+	for col, val := range e {
+		parts = append(parts, fmt.Sprintf("%s = ?", col))
+		args = append(args, val)
+	}
+	return strings.Join(parts, " AND "), args
+}
+
+// In builds a `col IN (?, ?, ...)` predicate.
+type In struct {
+	Col    string
+	Values []interface{}
+}
+
+func (i In) ToSQL() (string, []interface{}) {
+	if len(i.Values) == 0 {
+		return "1=0", nil
+	}
+	placeholders := strings.Repeat("?,", len(i.Values))
+	placeholders = strings.TrimSuffix(placeholders, ",")
+	return fmt.Sprintf("%s IN (%s)", i.Col, placeholders), i.Values
+}
+
+// And combines conditions with AND, wrapping each in parens.
+type And []Cond
+
+func (a And) ToSQL() (string, []interface{}) {
+	return joinConds(a, " AND ")
+}
+
+// Or combines conditions with OR, wrapping each in parens.
+type Or []Cond
+
+func (o Or) ToSQL() (string, []interface{}) {
+	return joinConds(o, " OR ")
+}
+
+func joinConds(conds []Cond, sep string) (string, []interface{}) {
+	if len(conds) == 0 {
+		return "1=1", nil
+	}
+	parts := make([]string, 0, len(conds))
+	var args []interface{}
+	for _, c := range conds {
+		sql, a := c.ToSQL()
+		parts = append(parts, "("+sql+")")
+		args = append(args, a...)
+	}
+	return strings.Join(parts, sep), args
+}
+
+// SelectBuilder assembles a parameterized SELECT statement:
+//
+//	db.Select("id", "entity").From("entity_metadata").Where(db.Eq{"entity_type": "file"}).OrderBy("id DESC").Limit(20)
+type SelectBuilder struct {
+	client  *Client
+	cols    []string
+	table   string
+	cond    Cond
+	orderBy string
+	limit   int
+}
+
+// Select begins a SELECT query against c.
+func (c *Client) Select(cols ...string) *SelectBuilder {
+	return &SelectBuilder{client: c, cols: cols}
+}
+
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+func (b *SelectBuilder) Where(cond Cond) *SelectBuilder {
+	b.cond = cond
+	return b
+}
+
+func (b *SelectBuilder) OrderBy(clause string) *SelectBuilder {
+	b.orderBy = clause
+	return b
+}
+
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Build renders the statement and its bind args without executing it.
+func (b *SelectBuilder) Build() (string, []interface{}) {
+	cols := "*"
+	if len(b.cols) > 0 {
+		cols = strings.Join(b.cols, ", ")
+	}
+	var sb strings.Builder
+	var args []interface{}
+	fmt.Fprintf(&sb, "SELECT %s FROM %s", cols, b.table)
+	if b.cond != nil {
+		whereSQL, whereArgs := b.cond.ToSQL()
+		sb.WriteString(" WHERE ")
+		sb.WriteString(whereSQL)
+		args = append(args, whereArgs...)
+	}
+	if b.orderBy != "" {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(b.orderBy)
+	}
+	if b.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", b.limit)
+	}
+	return sb.String(), args
+}
+
+// Rows executes the built query and returns *sql.Rows for manual scanning
+// (see Scan/ScanRows in scan.go for the typed helper built on top of this).
+func (b *SelectBuilder) Rows() (*sql.Rows, error) {
+	conn, err := b.client.open()
+	if err != nil {
+		return nil, err
+	}
+	query, args := b.Build()
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	return rows, nil
+}
+
+// InsertBuilder assembles a parameterized INSERT statement.
+type InsertBuilder struct {
+	client *Client
+	table  string
+	values map[string]interface{}
+}
+
+// Insert begins an INSERT into table.
+func (c *Client) Insert(table string, values map[string]interface{}) *InsertBuilder {
+	return &InsertBuilder{client: c, table: table, values: values}
+}
+
+func (b *InsertBuilder) Build() (string, []interface{}) {
+	cols := make([]string, 0, len(b.values))
+	args := make([]interface{}, 0, len(b.values))
+	for col, val := range b.values {
+		cols = append(cols, col)
+		args = append(args, val)
+	}
+	placeholders := strings.Repeat("?,", len(cols))
+	placeholders = strings.TrimSuffix(placeholders, ",")
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", b.table, strings.Join(cols, ", "), placeholders)
+	return sql, args
+}
+
+// Exec runs the INSERT and returns the new row's rowid.
+func (b *InsertBuilder) Exec() (int64, error) {
+	conn, err := b.client.open()
+	if err != nil {
+		return 0, err
+	}
+	query, args := b.Build()
+	res, err := conn.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("insert failed: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// UpdateBuilder assembles a parameterized UPDATE statement.
+type UpdateBuilder struct {
+	client *Client
+	table  string
+	set    map[string]interface{}
+	cond   Cond
+}
+
+func (c *Client) Update(table string, set map[string]interface{}) *UpdateBuilder {
+	return &UpdateBuilder{client: c, table: table, set: set}
+}
+
+func (b *UpdateBuilder) Where(cond Cond) *UpdateBuilder {
+	b.cond = cond
+	return b
+}
+
+func (b *UpdateBuilder) Exec() (int64, error) {
+	conn, err := b.client.open()
+	if err != nil {
+		return 0, err
+	}
+	sets := make([]string, 0, len(b.set))
+	args := make([]interface{}, 0, len(b.set))
+	for col, val := range b.set {
+		sets = append(sets, fmt.Sprintf("%s = ?", col))
+		args = append(args, val)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", b.table, strings.Join(sets, ", "))
+	if b.cond != nil {
+		whereSQL, whereArgs := b.cond.ToSQL()
+		query += " WHERE " + whereSQL
+		args = append(args, whereArgs...)
+	}
+	res, err := conn.Exec(query+";", args...)
+	if err != nil {
+		return 0, fmt.Errorf("update failed: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteBuilder assembles a parameterized DELETE statement.
+type DeleteBuilder struct {
+	client *Client
+	table  string
+	cond   Cond
+}
+
+func (c *Client) Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{client: c, table: table}
+}
+
+func (b *DeleteBuilder) Where(cond Cond) *DeleteBuilder {
+	b.cond = cond
+	return b
+}
+
+func (b *DeleteBuilder) Exec() (int64, error) {
+	conn, err := b.client.open()
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("DELETE FROM %s", b.table)
+	var args []interface{}
+	if b.cond != nil {
+		whereSQL, whereArgs := b.cond.ToSQL()
+		query += " WHERE " + whereSQL
+		args = whereArgs
+	}
+	res, err := conn.Exec(query+";", args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete failed: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Exec runs a parameterized statement directly, for the handful of cases
+// the builder above doesn't cover.
+func (c *Client) Exec(query string, args ...interface{}) (n int64, err error) {
+	defer c.observe(query, time.Now(), &err)()
+	conn, err := c.open()
+	if err != nil {
+		return 0, err
+	}
+	res, err := conn.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("exec failed: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,233 @@
+// Package db wraps the memory store's SQLite access. Historically this
+// shelled out to the `sqlite3` CLI for every query; Client now holds a
+// single long-lived *sql.DB over a CGO-free driver so callers don't pay a
+// fork+exec per query and can bind parameters instead of interpolating SQL.
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-turbo-search/memorydb/internal/metrics"
+
+	_ "modernc.org/sqlite"
+)
+
+// trackedTables are the tables Run/RunSQL/Exec calls report metrics
+// against by name; anything else is reported under table="" so the
+// op/table/status cardinality stays bounded.
+var trackedTables = []string{"sessions", "knowledge", "facts", "token_metrics"}
+
+// Client is the single entry point commands use to talk to memory.db. It is
+// safe for concurrent use; the underlying *sql.DB pools its own connections.
+type Client struct {
+	path string
+	mu   sync.Mutex
+	conn *sql.DB
+	// Metrics, if set, receives a counter/histogram observation for every
+	// Run/RunSQL/Exec call. It is nil by default so callers that don't
+	// care about metrics (tests, one-off tools) pay nothing for them; see
+	// commands.App, which attaches one shared registry for
+	// CmdServeMetrics to render.
+	Metrics *metrics.Registry
+}
+
+// New opens (lazily, on first query) the SQLite database at path.
+func New(path string) *Client {
+	return &Client{path: path}
+}
+
+func (c *Client) open() (*sql.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return c.conn, nil
+	}
+	conn, err := sql.Open("sqlite", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", c.path, err)
+	}
+	c.conn = conn
+	return c.conn, nil
+}
+
+// DB exposes the underlying *sql.DB for callers that want to use the query
+// builder or run parameterized statements directly.
+func (c *Client) DB() (*sql.DB, error) {
+	return c.open()
+}
+
+// Close releases the underlying connection pool.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Run executes a query and renders the result the way the old `sqlite3`
+// CLI did: pipe-separated rows by default, or a JSON array of objects when
+// the first argument is "-json". A leading "-separator", "<sep>" pair picks
+// a different column separator. This exists so the many call sites built
+// around that output format keep working while they're migrated one at a
+// time onto Scan/the query builder (see internal/models and chunk1-6).
+func (c *Client) Run(args ...string) (result string, err error) {
+	separator := "|"
+	asJSON := false
+	query := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-json":
+			asJSON = true
+		case "-separator":
+			if i+1 < len(args) {
+				separator = args[i+1]
+				i++
+			}
+		default:
+			query = args[i]
+		}
+	}
+	if query == "" {
+		return "", fmt.Errorf("db.Run: no query given")
+	}
+	defer c.observe(query, time.Now(), &err)()
+
+	conn, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	rows, err := conn.Query(query)
+	if err != nil {
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	if asJSON {
+		return renderJSONRows(rows, cols)
+	}
+	return renderSeparatedRows(rows, cols, separator)
+}
+
+// RunSQL executes one or more semicolon-separated statements (schema
+// scripts, multi-statement migrations) and returns any textual output from
+// the final statement, matching the old CLI's behavior of printing the last
+// SELECT's result.
+func (c *Client) RunSQL(script string) (string, error) {
+	conn, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	statements := splitStatements(script)
+	var out string
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		stmtErr := func() (stmtErr error) {
+			defer c.observe(stmt, time.Now(), &stmtErr)()
+			rows, err := conn.Query(stmt)
+			if err != nil {
+				if _, execErr := conn.Exec(stmt); execErr != nil {
+					return fmt.Errorf("statement failed: %w", execErr)
+				}
+				return nil
+			}
+			defer rows.Close()
+			cols, _ := rows.Columns()
+			out, _ = renderSeparatedRows(rows, cols, "|")
+			return nil
+		}()
+		if stmtErr != nil {
+			return "", stmtErr
+		}
+	}
+	return out, nil
+}
+
+// ScalarInt runs a query expected to return a single integer column/row.
+func (c *Client) ScalarInt(query string) (int, error) {
+	conn, err := c.open()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if err := conn.QueryRow(query).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// HasTable reports whether a table exists in sqlite_master.
+func (c *Client) HasTable(name string) bool {
+	n, err := c.ScalarInt(fmt.Sprintf(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='%s';", SQLQuote(name)))
+	return err == nil && n > 0
+}
+
+// SQLQuote escapes a string for embedding in a single-quoted SQL literal.
+// Prefer parameterized queries (see builder.go) for anything new; this
+// remains for the call sites that still build SQL via fmt.Sprintf.
+func SQLQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+func splitStatements(script string) []string {
+	return strings.Split(script, ";")
+}
+
+// observe returns a function to be deferred right after classifying query,
+// so the call site reports the operation's outcome (via the *error it was
+// handed) and latency to c.Metrics once the surrounding function returns.
+// It is a no-op if no Metrics registry is attached.
+func (c *Client) observe(query string, start time.Time, errp *error) func() {
+	if c.Metrics == nil {
+		return func() {}
+	}
+	op, table := classifyQuery(query)
+	return func() {
+		status := "ok"
+		if errp != nil && *errp != nil {
+			status = "err"
+		}
+		c.Metrics.Observe(op, table, status, time.Since(start))
+	}
+}
+
+// classifyQuery infers the Prometheus op/table labels for a query: op is
+// "insert", "select", or "schema" (anything else — CREATE/ALTER/DROP/
+// PRAGMA/UPDATE/DELETE, or a bare migration script); table is the first of
+// trackedTables found in the query text, or "" if none matched.
+func classifyQuery(query string) (op, table string) {
+	trimmed := strings.TrimSpace(query)
+	firstWord := trimmed
+	if i := strings.IndexAny(trimmed, " \t\n("); i >= 0 {
+		firstWord = trimmed[:i]
+	}
+	switch strings.ToUpper(firstWord) {
+	case "INSERT":
+		op = "insert"
+	case "SELECT":
+		op = "select"
+	default:
+		op = "schema"
+	}
+
+	lower := strings.ToLower(query)
+	for _, t := range trackedTables {
+		if strings.Contains(lower, t) {
+			table = t
+			break
+		}
+	}
+	return op, table
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// renderSeparatedRows formats rows as lines joined by separator, mirroring
+// `sqlite3 -separator <sep>` output so existing tab/pipe-parsing call sites
+// keep working unmodified.
+func renderSeparatedRows(rows *sql.Rows, cols []string, separator string) (string, error) {
+	var lines []string
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		cells := make([]string, len(cols))
+		for i, v := range values {
+			cells[i] = cellToString(v)
+		}
+		lines = append(lines, strings.Join(cells, separator))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// renderJSONRows formats rows as a JSON array of objects, mirroring
+// `sqlite3 -json` output.
+func renderJSONRows(rows *sql.Rows, cols []string) (string, error) {
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	records := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", err
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, c := range cols {
+			row[c] = jsonifyCell(values[i])
+		}
+		records = append(records, row)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(records)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func cellToString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case string:
+		return t
+	default:
+		b, _ := json.Marshal(t)
+		return strings.Trim(string(b), `"`)
+	}
+}
+
+func jsonifyCell(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Scan runs query against c and decodes each result row into a new T,
+// matching columns to T's fields by `db:"column_name"` struct tag. It's the
+// typed replacement for the Run+strings.SplitN parsing callers like
+// bruteForceVSearch and consolidate used to do by hand: a summary or fact
+// containing the separator character (a tab, a pipe, a newline) used to
+// silently corrupt those splits, where here it's just a column value.
+//
+// T must be a struct; every selected column must have a matching `db` tag
+// on some field of T, and NULL columns aren't expected, so queries should
+// COALESCE nullable columns rather than relying on sql.NullString fields.
+func Scan[T any](c *Client, query string, args ...interface{}) ([]T, error) {
+	conn, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	return ScanRows[T](rows)
+}
+
+// ScanRows decodes rows into a slice of T by `db` struct tag, the same way
+// Scan does. It takes ownership of rows and closes it before returning.
+func ScanRows[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var zero T
+	fieldIdx, err := dbFieldIndex(reflect.TypeOf(zero), cols)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+	for rows.Next() {
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		dest := make([]interface{}, len(cols))
+		for i, idx := range fieldIdx {
+			dest[i] = v.Field(idx).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// dbFieldIndex maps each column in cols to the index of the struct field on
+// t tagged `db:"<column>"`, in column order.
+func dbFieldIndex(t reflect.Type, cols []string) ([]int, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("db.Scan: %s is not a struct", t)
+	}
+	byTag := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("db"); tag != "" {
+			byTag[tag] = i
+		}
+	}
+	idx := make([]int, len(cols))
+	for i, col := range cols {
+		fieldIdx, ok := byTag[col]
+		if !ok {
+			return nil, fmt.Errorf("db.Scan: %s has no field tagged db:%q for column %q", t, col, col)
+		}
+		idx[i] = fieldIdx
+	}
+	return idx, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+)
+
+type scanTestRow struct {
+	ID    int    `db:"id"`
+	Text  string `db:"text"`
+	Other string `db:"other"`
+}
+
+// TestScanRoundTripsAwkwardText proves Scan carries a summary/fact value
+// through untouched even when it contains the characters that used to be
+// separators for db.Client.Run's text output (tab, pipe, newline, NUL) —
+// the exact corruption chunk1-6 was filed to fix.
+func TestScanRoundTripsAwkwardText(t *testing.T) {
+	c := New(":memory:")
+	if _, err := c.Exec(`CREATE TABLE scan_test (id INTEGER PRIMARY KEY, text TEXT, other TEXT);`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	cases := []string{
+		"tab\tseparated\tvalue",
+		"pipe|delimited|value",
+		"multi\nline\nvalue",
+		"nul\x00byte\x00value",
+		"plain value",
+	}
+	for i, text := range cases {
+		if _, err := c.Exec(`INSERT INTO scan_test (id, text, other) VALUES (?, ?, ?);`, i+1, text, "sidecar"); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+
+	rows, err := Scan[scanTestRow](c, `SELECT id, text, other FROM scan_test ORDER BY id;`)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(rows) != len(cases) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(cases))
+	}
+	for i, want := range cases {
+		if rows[i].Text != want {
+			t.Errorf("row %d: Text = %q, want %q", i, rows[i].Text, want)
+		}
+		if rows[i].Other != "sidecar" {
+			t.Errorf("row %d: Other = %q, want %q (a corrupted split would have bled text into this column)", i, rows[i].Other, "sidecar")
+		}
+	}
+}
+
+// TestScanMissingColumnTag errors instead of silently dropping data when a
+// selected column has no matching db tag on T.
+func TestScanMissingColumnTag(t *testing.T) {
+	c := New(":memory:")
+	if _, err := c.Exec(`CREATE TABLE scan_test2 (id INTEGER PRIMARY KEY, untagged TEXT);`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := c.Exec(`INSERT INTO scan_test2 (id, untagged) VALUES (1, 'x');`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if _, err := Scan[scanTestRow](c, `SELECT id, untagged FROM scan_test2;`); err == nil {
+		t.Fatal("expected an error for a column with no matching db tag, got nil")
+	}
+}
@@ -0,0 +1,67 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ollamaProvider embeds texts one at a time through Ollama's /api/embeddings
+// endpoint, which has no native batch form.
+type ollamaProvider struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOllamaProvider builds a Provider backed by a local or remote Ollama
+// server at baseURL.
+func NewOllamaProvider(baseURL, model string) Provider {
+	return &ollamaProvider{
+		baseURL: baseURL,
+		model:   model,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (o *ollamaProvider) Name() string  { return "ollama" }
+func (o *ollamaProvider) Model() string { return o.model }
+
+func (o *ollamaProvider) Embed(texts []string) ([][]float64, error) {
+	vecs := make([][]float64, len(texts))
+	for i, text := range texts {
+		vec, err := o.embedOne(text)
+		if err != nil {
+			return nil, fmt.Errorf("ollama embed %d/%d: %w", i+1, len(texts), err)
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
+func (o *ollamaProvider) embedOne(text string) ([]float64, error) {
+	body, err := json.Marshal(map[string]string{"model": o.model, "prompt": text})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.client.Post(o.baseURL+"/api/embeddings", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+	var out struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Embedding) == 0 {
+		return nil, fmt.Errorf("ollama returned an empty embedding")
+	}
+	return out.Embedding, nil
+}
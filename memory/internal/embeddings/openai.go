@@ -0,0 +1,78 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// openAIProvider embeds texts through any OpenAI-compatible /v1/embeddings
+// endpoint, which accepts a batch of inputs per request.
+type openAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAIProvider builds a Provider backed by an OpenAI-compatible
+// embeddings API at baseURL, authenticated with apiKey.
+func NewOpenAIProvider(baseURL, apiKey, model string) Provider {
+	return &openAIProvider{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (o *openAIProvider) Name() string  { return "openai" }
+func (o *openAIProvider) Model() string { return o.model }
+
+func (o *openAIProvider) Embed(texts []string) ([][]float64, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.baseURL+"/embeddings", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai embeddings returned status %d", resp.StatusCode)
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("openai embeddings returned %d vector(s) for %d input(s)", len(out.Data), len(texts))
+	}
+	vecs := make([][]float64, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("openai embeddings returned out-of-range index %d", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
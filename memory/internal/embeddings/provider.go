@@ -0,0 +1,105 @@
+// Package embeddings provides pluggable backends for turning text into
+// vectors: a native Ollama HTTP client, an OpenAI-compatible HTTP client,
+// and a persistent-process "script" backend that keeps embeddings.sh
+// loaded in a serve loop instead of forking it once per call.
+package embeddings
+
+import (
+	"fmt"
+	"time"
+)
+
+// Provider turns a batch of texts into one vector per text, in order.
+type Provider interface {
+	// Embed returns len(texts) vectors, one per input text, in the same order.
+	Embed(texts []string) ([][]float64, error)
+	// Name identifies the backend ("ollama", "openai", or "script"), and
+	// Model identifies the embedding model it's configured to use — both
+	// get recorded in vector_meta so mismatched DBs can be detected.
+	Name() string
+	Model() string
+}
+
+// Config selects and configures a Provider, typically loaded from
+// <memory-dir>/embedding-config.json.
+type Config struct {
+	Provider string `json:"provider"` // "ollama" (default), "openai", or "script"
+	Model    string `json:"model"`
+	BaseURL  string `json:"base_url"`
+	APIKey   string `json:"api_key"`
+}
+
+// New builds the Provider described by cfg. scriptPath is only used when
+// cfg.Provider is "script".
+func New(cfg Config, scriptPath string) (Provider, error) {
+	switch cfg.Provider {
+	case "", "ollama":
+		model := cfg.Model
+		if model == "" {
+			model = "bge-small-en"
+		}
+		base := cfg.BaseURL
+		if base == "" {
+			base = "http://localhost:11434"
+		}
+		return NewOllamaProvider(base, model), nil
+	case "openai":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("openai embedding provider requires a model")
+		}
+		base := cfg.BaseURL
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+		return NewOpenAIProvider(base, cfg.APIKey, cfg.Model), nil
+	case "script":
+		return NewScriptProvider(scriptPath)
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+	}
+}
+
+// WithRetry wraps p so Embed retries transient failures with exponential
+// backoff (base, base*2, base*4, ...) up to maxAttempts total tries.
+func WithRetry(p Provider, maxAttempts int, base time.Duration) Provider {
+	return &retryProvider{Provider: p, maxAttempts: maxAttempts, base: base}
+}
+
+type retryProvider struct {
+	Provider
+	maxAttempts int
+	base        time.Duration
+}
+
+func (r *retryProvider) Embed(texts []string) ([][]float64, error) {
+	var lastErr error
+	wait := r.base
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		vecs, err := r.Provider.Embed(texts)
+		if err == nil {
+			return vecs, nil
+		}
+		lastErr = err
+		if attempt < r.maxAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	return nil, fmt.Errorf("embed failed after %d attempt(s): %w", r.maxAttempts, lastErr)
+}
+
+// Batches splits texts into chunks of at most size, preserving order.
+func Batches(texts []string, size int) [][]string {
+	if size <= 0 {
+		size = len(texts)
+	}
+	var out [][]string
+	for i := 0; i < len(texts); i += size {
+		end := i + size
+		if end > len(texts) {
+			end = len(texts)
+		}
+		out = append(out, texts[i:end])
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package embeddings
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// scriptRequest is one line of the serve-mode protocol sent to
+// embeddings.sh on stdin.
+type scriptRequest struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// scriptResponse is one line read back from embeddings.sh on stdout.
+type scriptResponse struct {
+	ID    int       `json:"id"`
+	Vec   []float64 `json:"vec"`
+	Error string    `json:"error"`
+}
+
+// scriptProvider keeps `embeddings.sh serve` running as a long-lived child
+// process and feeds it one line-delimited JSON request per text, instead of
+// forking a fresh process (and reloading the model) for every call.
+type scriptProvider struct {
+	path string
+	mu   sync.Mutex
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+	nextID int
+}
+
+// NewScriptProvider starts `scriptPath serve` in the background and returns
+// a Provider that speaks to it over stdin/stdout.
+func NewScriptProvider(scriptPath string) (Provider, error) {
+	p := &scriptProvider{path: scriptPath}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *scriptProvider) Name() string  { return "script" }
+func (p *scriptProvider) Model() string { return p.path }
+
+func (p *scriptProvider) start() error {
+	cmd := exec.Command(p.path, "serve")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s serve: %w", p.path, err)
+	}
+	p.cmd = cmd
+	p.stdin = stdin
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	p.stdout = scanner
+	return nil
+}
+
+func (p *scriptProvider) Embed(texts []string) ([][]float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vecs := make([][]float64, len(texts))
+	for i, text := range texts {
+		id := p.nextID
+		p.nextID++
+		reqLine, err := json.Marshal(scriptRequest{ID: id, Text: text})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.stdin.Write(append(reqLine, '\n')); err != nil {
+			return nil, fmt.Errorf("writing to embeddings script: %w", err)
+		}
+		if !p.stdout.Scan() {
+			if err := p.stdout.Err(); err != nil {
+				return nil, fmt.Errorf("reading from embeddings script: %w", err)
+			}
+			return nil, fmt.Errorf("embeddings script closed its output unexpectedly")
+		}
+		var resp scriptResponse
+		if err := json.Unmarshal(p.stdout.Bytes(), &resp); err != nil {
+			return nil, fmt.Errorf("parsing embeddings script response: %w", err)
+		}
+		if resp.Error != "" {
+			return nil, fmt.Errorf("embeddings script: %s", resp.Error)
+		}
+		if resp.ID != id {
+			return nil, fmt.Errorf("embeddings script responded to request %d with id %d", id, resp.ID)
+		}
+		vecs[i] = resp.Vec
+	}
+	return vecs, nil
+}
@@ -0,0 +1,289 @@
+// Package migrations replaces the ad-hoc init/init-metadata/init-vector/
+// init-token-metrics commands with a single ordered list of numbered
+// migrations, each recorded in a schema_migrations table as it's applied.
+// The SQL is embedded with go:embed so the binary stays self-contained and
+// ScriptDir is no longer required to bring up a fresh database.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// Migration is one schema step. Up and Down are raw SQL scripts (possibly
+// multiple statements) rather than Go funcs, since every migration so far
+// is a pure schema change; a Go-func variant can be added if one needs to
+// backfill data.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// All is the ordered list of known migrations, loaded once from the
+// embedded sql/ directory.
+var All = mustLoadMigrations()
+
+func mustLoadMigrations() []Migration {
+	migrations, err := loadMigrations()
+	if err != nil {
+		panic(err)
+	}
+	return migrations
+}
+
+func loadMigrations() ([]Migration, error) {
+	entries, err := sqlFS.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		isDown := strings.HasSuffix(name, ".down.sql")
+		var version int
+		var label string
+		if _, err := fmt.Sscanf(name, "%04d_", &version); err != nil {
+			continue
+		}
+		label = strings.TrimSuffix(strings.TrimSuffix(name, ".down.sql"), ".sql")
+		label = strings.TrimPrefix(label, fmt.Sprintf("%04d_", version))
+
+		content, err := sqlFS.ReadFile("sql/" + name)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if isDown {
+			m.Down = string(content)
+		} else {
+			m.Up = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// splitStatements strips full-line "--" comments (migration files here
+// never put one mid-statement) before splitting on ";", so a semicolon
+// inside a comment's prose — like 0006_compression.down.sql's "pre-3.35;
+// same tradeoff..." — can't be mistaken for a statement terminator.
+func splitStatements(sqlText string) []string {
+	var kept strings.Builder
+	for _, line := range strings.Split(sqlText, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+	}
+	var stmts []string
+	for _, stmt := range strings.Split(kept.String(), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// Migrator applies and tracks migrations against a single *sql.DB.
+type Migrator struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+func (m *Migrator) ensureMigrationsTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);`)
+	return err
+}
+
+// AppliedVersions returns the set of migration versions already recorded.
+func (m *Migrator) AppliedVersions() (map[int]bool, error) {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query("SELECT version FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]bool{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// EnsureLatest applies every migration newer than the current version. This
+// is the single call App.EnsureSchema makes in place of the old
+// init/init-metadata/init-vector/init-token-metrics dance.
+func (m *Migrator) EnsureLatest() error {
+	return m.Up(0)
+}
+
+// Up applies up to `count` pending migrations in order (count<=0 means all).
+func (m *Migrator) Up(count int) error {
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		return err
+	}
+	done := 0
+	for _, mig := range All {
+		if applied[mig.Version] {
+			continue
+		}
+		if err := m.applyUp(mig); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		done++
+		if count > 0 && done >= count {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) applyUp(mig Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(mig.Up) {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?);", mig.Version, mig.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Down rolls back the `count` most recently applied migrations (count<=0
+// means just the latest one).
+func (m *Migrator) Down(count int) error {
+	if count <= 0 {
+		count = 1
+	}
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		return err
+	}
+	for i := len(All) - 1; i >= 0 && count > 0; i-- {
+		mig := All[i]
+		if !applied[mig.Version] {
+			continue
+		}
+		if err := m.applyDown(mig); err != nil {
+			return fmt.Errorf("migration %04d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		count--
+	}
+	return nil
+}
+
+func (m *Migrator) applyDown(mig Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(mig.Down) {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?;", mig.Version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// To migrates up or down to exactly `target` version.
+func (m *Migrator) To(target int) error {
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		return err
+	}
+	current := 0
+	for v := range applied {
+		if v > current {
+			current = v
+		}
+	}
+	if target > current {
+		for _, mig := range All {
+			if mig.Version > current && mig.Version <= target && !applied[mig.Version] {
+				if err := m.applyUp(mig); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for i := len(All) - 1; i >= 0; i-- {
+		mig := All[i]
+		if mig.Version <= target {
+			continue
+		}
+		if applied[mig.Version] {
+			if err := m.applyDown(mig); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Status describes one migration's applied state, for `memorydb migrate status`.
+type Status struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+func (m *Migrator) StatusReport() ([]Status, error) {
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	report := make([]Status, 0, len(All))
+	for _, mig := range All {
+		report = append(report, Status{Version: mig.Version, Name: mig.Name, Applied: applied[mig.Version]})
+	}
+	return report, nil
+}
@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestEnsureLatestFromEmpty brings a brand-new database all the way up,
+// the path App.EnsureSchema takes for a fresh `.claude-memory` directory.
+func TestEnsureLatestFromEmpty(t *testing.T) {
+	conn := openTestDB(t)
+	m := New(conn)
+	if err := m.EnsureLatest(); err != nil {
+		t.Fatalf("EnsureLatest: %v", err)
+	}
+
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	for _, mig := range All {
+		if !applied[mig.Version] {
+			t.Errorf("migration %04d_%s was not applied", mig.Version, mig.Name)
+		}
+	}
+
+	status, err := m.StatusReport()
+	if err != nil {
+		t.Fatalf("StatusReport: %v", err)
+	}
+	if len(status) != len(All) {
+		t.Fatalf("StatusReport returned %d entries, want %d", len(status), len(All))
+	}
+	for _, s := range status {
+		if !s.Applied {
+			t.Errorf("status for %04d_%s reports unapplied", s.Version, s.Name)
+		}
+	}
+}
+
+// TestEnsureLatestFromV1 simulates a database that already has the first
+// migration applied, confirming EnsureLatest only applies what's missing.
+func TestEnsureLatestFromV1(t *testing.T) {
+	if len(All) < 2 {
+		t.Skip("need at least two migrations to exercise a partial upgrade")
+	}
+	conn := openTestDB(t)
+	m := New(conn)
+	if err := m.Up(1); err != nil {
+		t.Fatalf("Up(1): %v", err)
+	}
+
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	if len(applied) != 1 || !applied[All[0].Version] {
+		t.Fatalf("expected only %04d_%s applied, got %v", All[0].Version, All[0].Name, applied)
+	}
+
+	if err := m.EnsureLatest(); err != nil {
+		t.Fatalf("EnsureLatest: %v", err)
+	}
+	applied, err = m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	for _, mig := range All {
+		if !applied[mig.Version] {
+			t.Errorf("migration %04d_%s was not applied after EnsureLatest", mig.Version, mig.Name)
+		}
+	}
+}
+
+// TestEnsureLatestFromPartiallyMigrated exercises a database stopped partway
+// through the migration list (e.g. a process killed mid-upgrade), confirming
+// EnsureLatest picks up from wherever AppliedVersions says it left off.
+func TestEnsureLatestFromPartiallyMigrated(t *testing.T) {
+	if len(All) < 3 {
+		t.Skip("need at least three migrations to exercise a mid-list resume")
+	}
+	conn := openTestDB(t)
+	m := New(conn)
+	half := len(All) / 2
+	if err := m.Up(half); err != nil {
+		t.Fatalf("Up(%d): %v", half, err)
+	}
+
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	if len(applied) != half {
+		t.Fatalf("got %d applied migrations, want %d", len(applied), half)
+	}
+
+	if err := m.EnsureLatest(); err != nil {
+		t.Fatalf("EnsureLatest: %v", err)
+	}
+	applied, err = m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	if len(applied) != len(All) {
+		t.Fatalf("got %d applied migrations after EnsureLatest, want %d", len(applied), len(All))
+	}
+}
+
+// TestDownRollsBackLatest confirms Down(1) undoes exactly the most recently
+// applied migration and removes its schema_migrations row.
+func TestDownRollsBackLatest(t *testing.T) {
+	conn := openTestDB(t)
+	m := New(conn)
+	if err := m.EnsureLatest(); err != nil {
+		t.Fatalf("EnsureLatest: %v", err)
+	}
+	if err := m.Down(1); err != nil {
+		t.Fatalf("Down(1): %v", err)
+	}
+
+	applied, err := m.AppliedVersions()
+	if err != nil {
+		t.Fatalf("AppliedVersions: %v", err)
+	}
+	last := All[len(All)-1]
+	if applied[last.Version] {
+		t.Errorf("migration %04d_%s should have been rolled back", last.Version, last.Name)
+	}
+	if len(applied) != len(All)-1 {
+		t.Fatalf("got %d applied migrations after Down(1), want %d", len(applied), len(All)-1)
+	}
+}
@@ -0,0 +1,228 @@
+// Package models defines the row types commands scan query results into via
+// db.Scan, in place of parsing db.Client.Run's tab/pipe-separated text.
+package models
+
+// SessionRow is a sessions row as read by consolidate's topic-overlap pass.
+type SessionRow struct {
+	ID      int    `db:"id"`
+	Topics  string `db:"topics"`
+	Summary string `db:"summary"`
+}
+
+// EmbeddedSessionRow is a sessions row carrying its (possibly compressed)
+// summary and embedding, as read by bruteForceVSearch. The query aliases
+// hex(summary_compressed)/hex(embedding) to the *_hex names below.
+type EmbeddedSessionRow struct {
+	ID                int    `db:"id"`
+	Summary           string `db:"summary"`
+	SummaryEncoding   string `db:"summary_encoding"`
+	SummaryCompressed string `db:"summary_compressed_hex"`
+	Embedding         string `db:"embedding_hex"`
+}
+
+// KnowledgeRow is a knowledge row as read by bruteForceVSearch.
+type KnowledgeRow struct {
+	ID                int    `db:"id"`
+	Area              string `db:"area"`
+	Summary           string `db:"summary"`
+	SummaryEncoding   string `db:"summary_encoding"`
+	SummaryCompressed string `db:"summary_compressed_hex"`
+	Embedding         string `db:"embedding_hex"`
+}
+
+// FactRow is a facts row as read by bruteForceVSearch.
+type FactRow struct {
+	ID         int    `db:"id"`
+	Fact       string `db:"fact"`
+	Encoding   string `db:"fact_encoding"`
+	Compressed string `db:"fact_compressed_hex"`
+	Embedding  string `db:"embedding_hex"`
+}
+
+// FactPairRow is one candidate-duplicate pair from consolidate's fact
+// self-join.
+type FactPairRow struct {
+	ID1 int `db:"id1"`
+	ID2 int `db:"id2"`
+}
+
+// EntityRow is an entity_metadata row's entity, type and source columns.
+type EntityRow struct {
+	Entity     string `db:"entity"`
+	EntityType string `db:"entity_type"`
+	SourceType string `db:"source_type"`
+	SourceID   int    `db:"source_id"`
+}
+
+// SearchRow is an FTS match result, as read by CmdSearch.
+type SearchRow struct {
+	SourceType string `db:"source_type" json:"source_type"`
+	SourceID   int    `db:"source_id" json:"source_id"`
+	Match      string `db:"match" json:"match"`
+}
+
+// EntityContextRow is an entity_metadata row joined against its source's
+// display text, as read by CmdEntitySearch.
+type EntityContextRow struct {
+	Entity     string `db:"entity"`
+	EntityType string `db:"entity_type"`
+	SourceType string `db:"source_type"`
+	SourceID   int    `db:"source_id"`
+	Context    string `db:"context"`
+}
+
+// FactTextRow is a single fact's text, as read by CmdContext's facts section.
+type FactTextRow struct {
+	Fact string `db:"fact"`
+}
+
+// KnowledgeAreaRow is an area/summary pair, as read by CmdContext's
+// knowledge-matching section.
+type KnowledgeAreaRow struct {
+	Area    string `db:"area"`
+	Summary string `db:"summary"`
+}
+
+// SessionSummaryRow is a single session's summary, as read by CmdContext's
+// recent-work section.
+type SessionSummaryRow struct {
+	Summary string `db:"summary"`
+}
+
+// MatchRow is a single FTS snippet match, as read by CmdContext's
+// related-context section.
+type MatchRow struct {
+	Match string `db:"match"`
+}
+
+// VectorIndexNodeRow is a vector_index row's id and hex-encoded HNSW node
+// blob, as read by loadVectorIndex.
+type VectorIndexNodeRow struct {
+	ID      string `db:"id"`
+	NodeHex string `db:"node_hex"`
+}
+
+// EmbeddingIDRow is a source row's id and hex-encoded embedding blob, as
+// read by syncVectorIndex and CmdReindex.
+type EmbeddingIDRow struct {
+	ID           int    `db:"id"`
+	EmbeddingHex string `db:"embedding_hex"`
+}
+
+// EntityRefCountRow is an entity/entity_type pair with its total reference
+// count, as read by kgGetEntities and kgGetTopEntities.
+type EntityRefCountRow struct {
+	Entity     string `db:"entity"`
+	EntityType string `db:"entity_type"`
+	RefCount   int    `db:"ref_count"`
+}
+
+// EntryRelationRow is one entry_relations row joined against each
+// endpoint's display label, as read by kgGetRelations.
+type EntryRelationRow struct {
+	FromType  string `db:"from_type"`
+	FromID    int    `db:"from_id"`
+	ToType    string `db:"to_type"`
+	ToID      int    `db:"to_id"`
+	Relation  string `db:"relation"`
+	FromLabel string `db:"from_label"`
+	ToLabel   string `db:"to_label"`
+}
+
+// CoOccurrenceRow is a pair of entities sharing sources, as read by
+// kgGetCoOccurrences.
+type CoOccurrenceRow struct {
+	EntityA       string `db:"entity_a"`
+	TypeA         string `db:"type_a"`
+	EntityB       string `db:"entity_b"`
+	TypeB         string `db:"type_b"`
+	SharedSources int    `db:"shared_sources"`
+}
+
+// TimelineEntryRow is a single sessions/knowledge row rendered onto the
+// shared timeline, as read by kgGetTimeline.
+type TimelineEntryRow struct {
+	ID        int    `db:"id"`
+	CreatedAt string `db:"created_at"`
+	Summary   string `db:"summary"`
+	EntryType string `db:"entry_type"`
+}
+
+// EntitySourceRow is one source an entity appears in, as read by
+// kgGetEntityDetail.
+type EntitySourceRow struct {
+	EntityType string `db:"entity_type"`
+	SourceType string `db:"source_type"`
+	SourceID   int    `db:"source_id"`
+	Context    string `db:"context"`
+}
+
+// RelationRow is a bare entry_relations row with no joined labels, as read
+// by kgGetEntityDetail's relations-for-this-entity query.
+type RelationRow struct {
+	FromType string `db:"from_type"`
+	FromID   int    `db:"from_id"`
+	ToType   string `db:"to_type"`
+	ToID     int    `db:"to_id"`
+	Relation string `db:"relation"`
+}
+
+// CoOccurrenceSummaryRow is an entity sharing sources with another, as read
+// by kgGetEntityDetail's co-occurrence query.
+type CoOccurrenceSummaryRow struct {
+	Entity     string `db:"entity"`
+	EntityType string `db:"entity_type"`
+	Shared     int    `db:"shared"`
+}
+
+// SessionPendingEmbeddingRow is a sessions row not yet embedded, as read by
+// queuePendingEmbeddings.
+type SessionPendingEmbeddingRow struct {
+	ID                int    `db:"id"`
+	Summary           string `db:"summary"`
+	SummaryEncoding   string `db:"summary_encoding"`
+	SummaryCompressed string `db:"summary_compressed_hex"`
+	Topics            string `db:"topics"`
+}
+
+// KnowledgePendingEmbeddingRow is a knowledge row not yet embedded, as read
+// by queuePendingEmbeddings.
+type KnowledgePendingEmbeddingRow struct {
+	ID                 int    `db:"id"`
+	Area               string `db:"area"`
+	Summary            string `db:"summary"`
+	SummaryEncoding    string `db:"summary_encoding"`
+	SummaryCompressed  string `db:"summary_compressed_hex"`
+	Patterns           string `db:"patterns"`
+	PatternsEncoding   string `db:"patterns_encoding"`
+	PatternsCompressed string `db:"patterns_compressed_hex"`
+}
+
+// FactPendingEmbeddingRow is a facts row not yet embedded, as read by
+// queuePendingEmbeddings.
+type FactPendingEmbeddingRow struct {
+	ID         int    `db:"id"`
+	Fact       string `db:"fact"`
+	Encoding   string `db:"fact_encoding"`
+	Compressed string `db:"fact_compressed_hex"`
+	Category   string `db:"category"`
+}
+
+// IDValueRow is an id paired with a single text column, aliased to "value"
+// by the caller's query. Used by migrateTextFields, which reads the same
+// shape from four different table/column combinations.
+type IDValueRow struct {
+	ID    int    `db:"id"`
+	Value string `db:"value"`
+}
+
+// SessionBudgetRow is a session_budgets row, the persisted watermarks a
+// budget.BudgetTracker is rebuilt from on each CmdAddTokenMetrics call.
+type SessionBudgetRow struct {
+	SessionID    int   `db:"session_id"`
+	SoftLimit    int64 `db:"soft_limit"`
+	HardLimit    int64 `db:"hard_limit"`
+	Used         int64 `db:"used"`
+	PeakUsage    int64 `db:"peak_usage"`
+	ActionsFired int64 `db:"actions_fired"`
+}
@@ -0,0 +1,144 @@
+// Package report renders CmdStats and CmdTokenStats through one Reporter
+// instead of each command hand-rolling its own text/JSON layout, so a
+// global --format flag can switch between text, JSON, NDJSON, and
+// Prometheus output without touching the commands themselves.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"claude-turbo-search/memorydb/internal/metrics"
+)
+
+// Row is one labeled value from a report — CmdStats' "sessions|5" line,
+// or a token_metrics aggregate. Text and NDJSON render one Row per line;
+// JSON renders the report struct as a whole.
+type Row struct {
+	Label string `json:"label"`
+	Value int64  `json:"value"`
+}
+
+// Reportable is implemented by StatsReport and TokenStatsReport: Rows
+// flattens the report for text/NDJSON/prom rendering, while Render's
+// "json" case marshals the report value itself, so JSON output keeps the
+// full nested shape (VectorStats, BudgetStats, ...) that Rows flattens
+// away.
+type Reportable interface {
+	Rows() []Row
+}
+
+// VectorStats is CmdStats' optional vector-search section.
+type VectorStats struct {
+	EmbeddedSessions  int64 `json:"embedded_sessions"`
+	EmbeddedKnowledge int64 `json:"embedded_knowledge"`
+	EmbeddedFacts     int64 `json:"embedded_facts"`
+	PendingEmbeddings int64 `json:"pending_embeddings"`
+}
+
+// BudgetStats is CmdStats' optional token-budget section.
+type BudgetStats struct {
+	TrackedSessions int64 `json:"tracked_sessions"`
+	PeakUsage       int64 `json:"peak_usage"`
+	ActionsFired    int64 `json:"actions_fired"`
+}
+
+// StatsReport is what CmdStats renders.
+type StatsReport struct {
+	DBFile    string       `json:"db_file"`
+	Sessions  int64        `json:"sessions"`
+	Knowledge int64        `json:"knowledge"`
+	Facts     int64        `json:"facts"`
+	Vector    *VectorStats `json:"vector,omitempty"`
+	Budget    *BudgetStats `json:"budget,omitempty"`
+}
+
+func (r StatsReport) Rows() []Row {
+	rows := []Row{
+		{"sessions", r.Sessions},
+		{"knowledge_areas", r.Knowledge},
+		{"facts", r.Facts},
+	}
+	if r.Vector != nil {
+		rows = append(rows,
+			Row{"embedded_sessions", r.Vector.EmbeddedSessions},
+			Row{"embedded_knowledge", r.Vector.EmbeddedKnowledge},
+			Row{"embedded_facts", r.Vector.EmbeddedFacts},
+			Row{"pending_embeddings", r.Vector.PendingEmbeddings},
+		)
+	}
+	if r.Budget != nil {
+		rows = append(rows,
+			Row{"token_budgets_tracked", r.Budget.TrackedSessions},
+			Row{"peak_usage", r.Budget.PeakUsage},
+			Row{"budget_actions_fired", r.Budget.ActionsFired},
+		)
+	}
+	return rows
+}
+
+// TokenStatsReport is what CmdTokenStats renders — the same aggregates
+// CmdServeMetrics publishes as gauges (see metrics.Gauges).
+type TokenStatsReport struct {
+	TrackedSessions    int64 `json:"tracked_sessions"`
+	TotalSearches      int64 `json:"total_searches"`
+	TotalFilesRead     int64 `json:"total_files_read"`
+	TotalFilesEdited   int64 `json:"total_files_edited"`
+	TotalTokensUsed    int64 `json:"total_tokens_used"`
+	TotalTokensWithout int64 `json:"total_tokens_without"`
+	TotalTokensSaved   int64 `json:"total_tokens_saved"`
+}
+
+func (r TokenStatsReport) Rows() []Row {
+	return []Row{
+		{"tracked_sessions", r.TrackedSessions},
+		{"total_searches", r.TotalSearches},
+		{"total_files_read", r.TotalFilesRead},
+		{"total_files_edited", r.TotalFilesEdited},
+		{"total_tokens_used", r.TotalTokensUsed},
+		{"total_tokens_without", r.TotalTokensWithout},
+		{"total_tokens_saved", r.TotalTokensSaved},
+	}
+}
+
+// Render writes v in format: "text" (the default) prints one
+// "label|value" line per Row, matching db.Client.Run's default
+// separated-output convention; "json" marshals v as a single object;
+// "ndjson" prints one JSON object per Row; "prom" prints one Prometheus
+// gauge block per Row, via metrics.RenderGauge.
+func Render(format string, v Reportable) (string, error) {
+	switch format {
+	case "", "text":
+		var b strings.Builder
+		for _, row := range v.Rows() {
+			fmt.Fprintf(&b, "%s|%d\n", row.Label, row.Value)
+		}
+		return b.String(), nil
+	case "json":
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	case "ndjson":
+		var b strings.Builder
+		for _, row := range v.Rows() {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return "", err
+			}
+			b.Write(data)
+			b.WriteByte('\n')
+		}
+		return b.String(), nil
+	case "prom":
+		var b strings.Builder
+		for _, row := range v.Rows() {
+			metrics.RenderGauge(&b, "memorydb_"+row.Label, "See memorydb stats.", row.Value)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtureStats and fixtureTokenStats are rendered against testdata/*.golden
+// for every format Render supports.
+var fixtureStats = StatsReport{
+	DBFile:    "/tmp/test.db",
+	Sessions:  3,
+	Knowledge: 2,
+	Facts:     5,
+	Vector: &VectorStats{
+		EmbeddedSessions:  1,
+		EmbeddedKnowledge: 2,
+		EmbeddedFacts:     3,
+		PendingEmbeddings: 4,
+	},
+	Budget: &BudgetStats{
+		TrackedSessions: 2,
+		PeakUsage:       100,
+		ActionsFired:    7,
+	},
+}
+
+var fixtureTokenStats = TokenStatsReport{
+	TrackedSessions:    4,
+	TotalSearches:      10,
+	TotalFilesRead:     20,
+	TotalFilesEdited:   5,
+	TotalTokensUsed:    1000,
+	TotalTokensWithout: 5000,
+	TotalTokensSaved:   4000,
+}
+
+func TestRenderGolden(t *testing.T) {
+	cases := []struct {
+		name   string
+		report Reportable
+	}{
+		{"stats", fixtureStats},
+		{"tokenstats", fixtureTokenStats},
+	}
+	formats := []string{"text", "json", "ndjson", "prom"}
+
+	for _, c := range cases {
+		for _, format := range formats {
+			got, err := Render(format, c.report)
+			if err != nil {
+				t.Fatalf("Render(%q, %s): %v", format, c.name, err)
+			}
+			goldenPath := filepath.Join("testdata", c.name+"_"+format+".golden")
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading %s: %v", goldenPath, err)
+			}
+			if got != string(want) {
+				t.Errorf("Render(%q, %s) = %q, want %q", format, c.name, got, string(want))
+			}
+		}
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, err := Render("yaml", fixtureStats); err == nil {
+		t.Fatal("expected an error for an unknown format, got nil")
+	}
+}
@@ -0,0 +1,301 @@
+//go:build badger
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// badgerStore persists sessions/knowledge/facts/token_metrics as keyed
+// buckets in an embedded badger database instead of SQLite tables: each row
+// is a JSON value under key "<table>:<id>" (the next id per table is
+// recovered on open by scanning those keys, see loadNextIDs), so
+// HasTable/CountRows/Insert/Query read the same shape of data the sqlite
+// backend's tables hold.
+type badgerStore struct {
+	path string
+
+	mu     sync.Mutex
+	db     *badger.DB
+	nextID map[string]int64
+}
+
+// New returns a badger-backed store over the (lazily created) database
+// directory at path, matching internal/db.Client's lazy-open-on-first-query
+// convention. Build with -tags badger to select this file over
+// sqlite.go/postgres.go.
+func New(path string) (MemoryStore, error) {
+	return &badgerStore{path: path, nextID: map[string]int64{}}, nil
+}
+
+func (s *badgerStore) open() (*badger.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db != nil {
+		return s.db, nil
+	}
+	db, err := badger.Open(badger.DefaultOptions(s.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open badger store at %s: %w", s.path, err)
+	}
+	nextID, err := loadNextIDs(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan existing ids in %s: %w", s.path, err)
+	}
+	s.db = db
+	s.nextID = nextID
+	return s.db, nil
+}
+
+// loadNextIDs scans every "<table>:<id>" key already in db and returns, per
+// table, the highest id found — so a store reopened by a later CLI
+// invocation resumes ids where the last one left off instead of restarting
+// every counter at 0 and overwriting existing rows.
+func loadNextIDs(db *badger.DB) (map[string]int64, error) {
+	nextID := map[string]int64{}
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			key := string(it.Item().Key())
+			idx := strings.LastIndex(key, ":")
+			if idx == -1 {
+				continue
+			}
+			table, idPart := key[:idx], key[idx+1:]
+			id, err := strconv.ParseInt(idPart, 10, 64)
+			if err != nil {
+				continue
+			}
+			if id > nextID[table] {
+				nextID[table] = id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nextID, nil
+}
+
+func bucketPrefix(table string) []byte {
+	return []byte(table + ":")
+}
+
+func rowKey(table string, id int64) []byte {
+	return []byte(fmt.Sprintf("%s:%d", table, id))
+}
+
+// HasTable reports whether any row has ever been written to table.
+func (s *badgerStore) HasTable(table string) bool {
+	db, err := s.open()
+	if err != nil {
+		return false
+	}
+	has := false
+	_ = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		it.Seek(bucketPrefix(table))
+		has = it.ValidForPrefix(bucketPrefix(table))
+		return nil
+	})
+	return has
+}
+
+// CountRows counts every row in table; where supports a single "col=value",
+// "col IS NULL" or "col IS NOT NULL" predicate (badger has no query planner
+// to do more), or "" for none.
+func (s *badgerStore) CountRows(table, where string) (int, error) {
+	rows, err := s.Query(table, nil, where)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// Insert JSON-encodes values under a fresh "<table>:<id>" key and returns
+// that id.
+func (s *badgerStore) Insert(table string, values map[string]interface{}) (int64, error) {
+	db, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	id := s.nextID[table] + 1
+	s.nextID[table] = id
+	s.mu.Unlock()
+
+	row := make(Row, len(values)+1)
+	for k, v := range values {
+		row[k] = v
+	}
+	row["id"] = id
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return 0, fmt.Errorf("encode row: %w", err)
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Set(rowKey(table, id), data)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("insert failed: %w", err)
+	}
+	return id, nil
+}
+
+// Query scans every row in table, decodes it, and applies where's filter
+// (if any) and cols projection (if any) in Go, since badger has no SQL
+// engine to push either down to.
+func (s *badgerStore) Query(table string, cols []string, where string) ([]Row, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	filter, hasFilter := parseFilter(where)
+
+	var out []Row
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := bucketPrefix(table)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var row Row
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &row)
+			})
+			if err != nil {
+				return fmt.Errorf("decode row: %w", err)
+			}
+			if hasFilter && !filter.matches(row) {
+				continue
+			}
+			out = append(out, projectRow(row, cols))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Exec isn't meaningful against a keyed bucket store the way it is for a
+// SQL engine; badger rows are always written through Insert.
+func (s *badgerStore) Exec(query string, args ...interface{}) (int64, error) {
+	return 0, fmt.Errorf("badger store: Exec is not supported, use Insert")
+}
+
+func (s *badgerStore) BeginTx() (Tx, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return &badgerTx{txn: db.NewTransaction(true)}, nil
+}
+
+func (s *badgerStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+type badgerTx struct {
+	txn *badger.Txn
+}
+
+func (t *badgerTx) Commit() error {
+	return t.txn.Commit()
+}
+
+func (t *badgerTx) Rollback() error {
+	t.txn.Discard()
+	return nil
+}
+
+// badgerFilterKind is the shape of the single predicate parseFilter
+// recognizes — badger has no query planner, so CountRows/Query can only
+// honor the handful of WHERE shapes the commands package actually sends.
+type badgerFilterKind int
+
+const (
+	badgerFilterEq badgerFilterKind = iota
+	badgerFilterIsNull
+	badgerFilterIsNotNull
+)
+
+type badgerFilter struct {
+	kind badgerFilterKind
+	col  string
+	val  string // only set for badgerFilterEq
+}
+
+func (f badgerFilter) matches(row Row) bool {
+	switch f.kind {
+	case badgerFilterIsNull:
+		return row[f.col] == nil
+	case badgerFilterIsNotNull:
+		return row[f.col] != nil
+	default:
+		return fmt.Sprintf("%v", row[f.col]) == f.val
+	}
+}
+
+// parseFilter recognizes "col=value", "col IS NULL" and "col IS NOT NULL" —
+// the WHERE shapes app.go's CmdStats and budget_actions.go send. Anything
+// else comes back !ok, matching every row, same as an empty where; that
+// includes token_drift.go's "measured_tokens_used > 0".
+func parseFilter(where string) (f badgerFilter, ok bool) {
+	where = strings.TrimSpace(where)
+	if where == "" {
+		return badgerFilter{}, false
+	}
+	if col, ok := trimSuffixFold(where, "IS NOT NULL"); ok {
+		return badgerFilter{kind: badgerFilterIsNotNull, col: strings.TrimSpace(col)}, true
+	}
+	if col, ok := trimSuffixFold(where, "IS NULL"); ok {
+		return badgerFilter{kind: badgerFilterIsNull, col: strings.TrimSpace(col)}, true
+	}
+	parts := strings.SplitN(where, "=", 2)
+	if len(parts) != 2 {
+		return badgerFilter{}, false
+	}
+	return badgerFilter{
+		kind: badgerFilterEq,
+		col:  strings.TrimSpace(parts[0]),
+		val:  strings.Trim(strings.TrimSpace(parts[1]), "'\""),
+	}, true
+}
+
+// trimSuffixFold reports whether s ends with suffix (case-insensitively,
+// since SQL keywords like IS NULL are conventionally written in caps but
+// callers may not), returning s with the suffix removed.
+func trimSuffixFold(s, suffix string) (string, bool) {
+	if len(s) < len(suffix) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return "", false
+	}
+	return s[:len(s)-len(suffix)], true
+}
+
+func projectRow(row Row, cols []string) Row {
+	if len(cols) == 0 {
+		return row
+	}
+	out := make(Row, len(cols))
+	for _, c := range cols {
+		out[c] = row[c]
+	}
+	return out
+}
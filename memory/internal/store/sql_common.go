@@ -0,0 +1,40 @@
+//go:build !badger
+
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// sqlTx adapts *sql.Tx to Tx for the two backends (sqlite, postgres) built
+// on database/sql.
+type sqlTx sql.Tx
+
+func (t *sqlTx) Commit() error   { return (*sql.Tx)(t).Commit() }
+func (t *sqlTx) Rollback() error { return (*sql.Tx)(t).Rollback() }
+
+// scanToRows drains rows into Row maps keyed by column name, the shared
+// tail end of sqliteStore.Query and postgresStore.Query.
+func scanToRows(rows *sql.Rows) ([]Row, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []Row
+	for rows.Next() {
+		dest := make([]interface{}, len(cols))
+		for i := range dest {
+			dest[i] = new(interface{})
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		row := make(Row, len(cols))
+		for i, col := range cols {
+			row[col] = *(dest[i].(*interface{}))
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
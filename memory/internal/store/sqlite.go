@@ -0,0 +1,79 @@
+//go:build !badger && !postgres
+
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"claude-turbo-search/memorydb/internal/db"
+)
+
+// sqliteStore is the default MemoryStore, backed by the same internal/db
+// client every other command already uses.
+type sqliteStore struct {
+	client *db.Client
+}
+
+// New opens (lazily, like internal/db.Client itself) the SQLite-backed
+// store at path.
+func New(path string) (MemoryStore, error) {
+	return &sqliteStore{client: db.New(path)}, nil
+}
+
+func (s *sqliteStore) HasTable(name string) bool {
+	return s.client.HasTable(name)
+}
+
+func (s *sqliteStore) CountRows(table, where string) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", table)
+	if strings.TrimSpace(where) != "" {
+		query += " WHERE " + where
+	}
+	return s.client.ScalarInt(query + ";")
+}
+
+func (s *sqliteStore) Insert(table string, values map[string]interface{}) (int64, error) {
+	return s.client.Insert(table, values).Exec()
+}
+
+func (s *sqliteStore) Query(table string, cols []string, where string) ([]Row, error) {
+	colList := "*"
+	if len(cols) > 0 {
+		colList = strings.Join(cols, ", ")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", colList, table)
+	if strings.TrimSpace(where) != "" {
+		query += " WHERE " + where
+	}
+	conn, err := s.client.DB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(query + ";")
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	return scanToRows(rows)
+}
+
+func (s *sqliteStore) Exec(query string, args ...interface{}) (int64, error) {
+	return s.client.Exec(query, args...)
+}
+
+func (s *sqliteStore) BeginTx() (Tx, error) {
+	conn, err := s.client.DB()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx failed: %w", err)
+	}
+	return (*sqlTx)(tx), nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.client.Close()
+}
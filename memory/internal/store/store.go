@@ -0,0 +1,60 @@
+// Package store defines the MemoryStore abstraction the stats, token-metrics
+// and budget commands (CmdStats, CmdTokenStats, ensureTokenMetricsTable,
+// CmdAddTokenMetrics, CmdTokenDrift, CmdSetBudget and the budget actions) are
+// built against, so the engine behind them can be swapped with a build tag
+// instead of a code change.
+// The default build (no tag, or "sqlite") keeps using internal/db's SQLite
+// client; "badger" and "postgres" tags select the other two files in this
+// package instead — exactly one of the three New functions is compiled into
+// any given binary, the same way SeaweedFS gates its elastic/sqlite/hdfs
+// filer store backends.
+//
+// Other commands (CmdAddSession, bruteForceVSearch, and the rest of the
+// SQL-heavy call sites) still talk to internal/db directly; porting them
+// onto MemoryStore is future work, one subsystem at a time, same as
+// internal/db.Scan's rollout in chunk1-6.
+package store
+
+// Row is one result row from Query, keyed by column name. Backends that
+// aren't relational (badger) build these by hand from their stored keys;
+// it's the lowest common denominator CountRows/Insert/Query/Exec can all
+// agree on.
+type Row map[string]interface{}
+
+// MemoryStore is the storage engine behind the stats subsystem: enough to
+// check schema presence, count and aggregate rows, and append new ones,
+// without the caller needing to know whether that's SQLite, badger, or
+// postgres underneath.
+type MemoryStore interface {
+	// HasTable reports whether a table/bucket has been initialized.
+	HasTable(name string) bool
+
+	// CountRows returns the number of rows/keys in table matching where (a
+	// SQL-style predicate for the relational backends, interpreted as a
+	// simple column=value filter by badger; pass "" for no filter).
+	CountRows(table, where string) (int, error)
+
+	// Insert appends one row/entry to table and returns its new id.
+	Insert(table string, values map[string]interface{}) (int64, error)
+
+	// Query returns every row/entry in table matching where, with cols
+	// selecting which fields to populate (nil/empty means all of them).
+	Query(table string, cols []string, where string) ([]Row, error)
+
+	// Exec runs a backend-native statement directly, for operations Query/
+	// Insert/CountRows don't cover (schema setup, deletes, raw SQL on the
+	// relational backends).
+	Exec(query string, args ...interface{}) (int64, error)
+
+	// BeginTx starts a transaction scoping a group of Insert/Exec calls.
+	BeginTx() (Tx, error)
+
+	// Close releases any connection or file handle the store is holding.
+	Close() error
+}
+
+// Tx scopes a group of writes to commit or roll back together.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
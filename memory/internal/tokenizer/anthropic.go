@@ -0,0 +1,16 @@
+package tokenizer
+
+// anthropicApprox is the same chars/4 approximation Anthropic documents as
+// a rough token-count estimate when no real tokenizer is available, kept
+// as a fast fallback that needs no embedded vocab.
+type anthropicApprox struct{}
+
+func (anthropicApprox) Name() string { return "anthropic-approx" }
+
+func (anthropicApprox) Count(text string) (int, error) {
+	n := len(text) / 4
+	if len(text)%4 != 0 {
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,166 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed assets/*.tiktoken
+var assetFS embed.FS
+
+// bpeTokenizer counts tokens by byte-pair merging, against a rank table
+// loaded from an embedded .tiktoken-format asset (the same "<base64
+// token> <rank>" per-line format tiktoken ships its cl100k_base.tiktoken/
+// o200k_base.tiktoken files in).
+//
+// The bundled assets are a bootstrap subset — every single byte plus a
+// few hundred of the commonest English merges — not the real ~100k-entry
+// tables, since those aren't available to embed here. Count still covers
+// every input (anything the bundled merges don't recognize falls back to
+// one token per byte), it just won't match the real encoder's count
+// exactly until assets/<encoding>.tiktoken is replaced with the genuine
+// rank file.
+type bpeTokenizer struct {
+	name  string
+	ranks map[string]int
+}
+
+func newBPETokenizer(encoding string) (*bpeTokenizer, error) {
+	data, err := assetFS.ReadFile(fmt.Sprintf("assets/%s.tiktoken", encoding))
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: no vocab asset for %q: %w", encoding, err)
+	}
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tokenBytes, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			continue
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		ranks[string(tokenBytes)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &bpeTokenizer{name: encoding, ranks: ranks}, nil
+}
+
+func (t *bpeTokenizer) Name() string { return t.name }
+
+// bpeNode is one token in the doubly-linked list Count merges over. alive
+// is cleared when a node is merged into its left neighbor, so a stale
+// bpeCandidate sitting in the heap can be recognized and skipped rather
+// than acted on.
+type bpeNode struct {
+	data       []byte
+	prev, next *bpeNode
+	alive      bool
+}
+
+// bpeCandidate is one still-possibly-mergeable adjacent pair. seq orders
+// same-rank candidates by the order they became adjacent, matching the
+// leftmost-first tie-break a linear left-to-right scan would make.
+type bpeCandidate struct {
+	rank        int
+	seq         int
+	left, right *bpeNode
+}
+
+type bpeHeap []*bpeCandidate
+
+func (h bpeHeap) Len() int { return len(h) }
+func (h bpeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+func (h bpeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *bpeHeap) Push(x interface{}) { *h = append(*h, x.(*bpeCandidate)) }
+func (h *bpeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Count merges text's UTF-8 bytes pairwise, always taking the lowest-rank
+// applicable pair first (tiktoken's own merge order), until no adjacent
+// pair in t.ranks applies. It tracks candidate pairs in a min-heap over a
+// doubly-linked token list rather than rescanning every adjacent pair on
+// every merge, so the cost is O(n log n) instead of O(n^2).
+func (t *bpeTokenizer) Count(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+
+	nodes := make([]*bpeNode, len(text))
+	for i := 0; i < len(text); i++ {
+		nodes[i] = &bpeNode{data: []byte{text[i]}, alive: true}
+		if i > 0 {
+			nodes[i-1].next = nodes[i]
+			nodes[i].prev = nodes[i-1]
+		}
+	}
+	count := len(nodes)
+
+	h := &bpeHeap{}
+	seq := 0
+	pushCandidate := func(left, right *bpeNode) {
+		if left == nil || right == nil {
+			return
+		}
+		rank, ok := t.ranks[string(left.data)+string(right.data)]
+		if !ok {
+			return
+		}
+		heap.Push(h, &bpeCandidate{rank: rank, seq: seq, left: left, right: right})
+		seq++
+	}
+	for _, n := range nodes {
+		pushCandidate(n, n.next)
+	}
+
+	for h.Len() > 0 {
+		c := heap.Pop(h).(*bpeCandidate)
+		if !c.left.alive || !c.right.alive || c.left.next != c.right {
+			continue
+		}
+		// A node that absorbed its right neighbor keeps its identity, so a
+		// candidate queued before that merge still looks adjacent; recheck
+		// that the pair as it stands now is the one that was ranked.
+		if r, ok := t.ranks[string(c.left.data)+string(c.right.data)]; !ok || r != c.rank {
+			continue
+		}
+		c.left.data = append(c.left.data, c.right.data...)
+		c.left.next = c.right.next
+		if c.right.next != nil {
+			c.right.next.prev = c.left
+		}
+		c.right.alive = false
+		count--
+
+		pushCandidate(c.left.prev, c.left)
+		pushCandidate(c.left, c.left.next)
+	}
+	return count, nil
+}
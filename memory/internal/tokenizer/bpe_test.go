@@ -0,0 +1,67 @@
+package tokenizer
+
+import "testing"
+
+// TestCountNoApplicableMerges proves Count falls back to one token per byte
+// when the rank table has no merge for any adjacent pair in the input —
+// the bundled bootstrap vocab's documented behavior for anything outside
+// its few hundred merges.
+func TestCountNoApplicableMerges(t *testing.T) {
+	tok := &bpeTokenizer{name: "test", ranks: map[string]int{}}
+	got, err := tok.Count("hello")
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 5 {
+		t.Errorf("Count = %d, want 5 (one token per byte)", got)
+	}
+}
+
+// TestCountTieBreaksLeftmostFirst covers two adjacent pairs that tie on
+// rank, which the heap's seq tie-break resolves leftmost first — the order
+// a linear left-to-right scan would take. "aaa" offers two equal-rank
+// candidates ("a","a") at positions 0-1 and 1-2: merging the leftmost
+// first leaves the merged "aa" node with no further applicable pair (ranks
+// has no "aaa" entry) and the 1-2 candidate pointing at a dead node, so
+// the result is 2 tokens. Either merge order yields 2 here, since Count
+// skips a candidate whose pair no longer matches its rank.
+func TestCountTieBreaksLeftmostFirst(t *testing.T) {
+	tok := &bpeTokenizer{name: "test", ranks: map[string]int{"aa": 1}}
+	got, err := tok.Count("aaa")
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 2 {
+		t.Errorf("Count = %d, want 2", got)
+	}
+}
+
+// TestCountReAdjacencyAfterMerge proves a merge's new left-side adjacency
+// is itself considered for further merging in the same pass (the
+// pushCandidate(c.left.prev, c.left) call after a merge), not just the
+// right-side one. "xab" only has "ab" as an initial candidate; merging it
+// makes "x" adjacent to the new "ab" node, which should be checked
+// against (and here, merged by) the "xab" entry.
+func TestCountReAdjacencyAfterMerge(t *testing.T) {
+	tok := &bpeTokenizer{name: "test", ranks: map[string]int{"ab": 1, "xab": 0}}
+	got, err := tok.Count("xab")
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("Count = %d, want 1", got)
+	}
+}
+
+// TestCountEmptyString proves the empty-input short circuit returns 0
+// without touching the heap/linked-list machinery.
+func TestCountEmptyString(t *testing.T) {
+	tok := &bpeTokenizer{name: "test", ranks: map[string]int{}}
+	got, err := tok.Count("")
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if got != 0 {
+		t.Errorf("Count = %d, want 0", got)
+	}
+}
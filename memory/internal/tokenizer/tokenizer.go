@@ -0,0 +1,33 @@
+// Package tokenizer counts how many tokens a string of text encodes to,
+// replacing CmdAddTokenMetrics' fixed per-search/per-file heuristic with a
+// measured count for the content actually read and written in a session.
+package tokenizer
+
+import "fmt"
+
+// Tokenizer counts tokens in text.
+type Tokenizer interface {
+	// Count returns the number of tokens text encodes to.
+	Count(text string) (int, error)
+	// Name identifies the encoding ("cl100k_base", "o200k_base", or
+	// "anthropic-approx"), recorded alongside measured_tokens_used so a
+	// drift report can tell which encoding produced a given count.
+	Name() string
+}
+
+// New builds the Tokenizer for encoding, defaulting to cl100k_base (the
+// encoding CmdAddTokenMetrics used before this existed had no real
+// equivalent to default to, so cl100k_base — the most common general-
+// purpose BPE vocab — is the safest default).
+func New(encoding string) (Tokenizer, error) {
+	switch encoding {
+	case "", "cl100k_base":
+		return newBPETokenizer("cl100k_base")
+	case "o200k_base":
+		return newBPETokenizer("o200k_base")
+	case "anthropic-approx":
+		return anthropicApprox{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer encoding %q", encoding)
+	}
+}
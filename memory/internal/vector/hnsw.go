@@ -0,0 +1,303 @@
+package vector
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// SearchResult is one ANN hit: the id handed to Add, and its similarity to
+// the query vector.
+type SearchResult struct {
+	ID    string
+	Score float64
+}
+
+// Index is the interface CmdVSearch queries instead of scanning every
+// embedding row. HNSW below is the only implementation; CmdVSearch falls
+// back to brute-force cosine scan when no persisted index exists yet.
+type Index interface {
+	Add(id string, vec []float64) error
+	Search(query []float64, k int) ([]SearchResult, error)
+	Size() int
+}
+
+type hnswNode struct {
+	ID        string
+	Vec       []float64
+	Layer     int
+	Neighbors map[int][]string // layer -> neighbor ids, up to M per layer
+}
+
+// HNSW is a small-world graph ANN index: each node sits on a random top
+// layer (geometric distribution), search descends greedily from the entry
+// point on the highest layer down to layer 0, and each layer keeps a
+// bounded candidate list via ef_construction/ef_search beam widths.
+type HNSW struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+
+	mu         sync.RWMutex
+	nodes      map[string]*hnswNode
+	entryPoint string
+	maxLayer   int
+	rnd        *rand.Rand
+}
+
+// NewHNSW builds an empty index. M is the max neighbors kept per layer per
+// node; efConstruction/efSearch are the beam widths used while inserting
+// and querying respectively.
+func NewHNSW(m, efConstruction, efSearch int) *HNSW {
+	if m <= 0 {
+		m = 16
+	}
+	if efConstruction <= 0 {
+		efConstruction = 200
+	}
+	if efSearch <= 0 {
+		efSearch = 64
+	}
+	return &HNSW{
+		M:              m,
+		EfConstruction: efConstruction,
+		EfSearch:       efSearch,
+		nodes:          map[string]*hnswNode{},
+		maxLayer:       -1,
+		rnd:            rand.New(rand.NewSource(1)),
+	}
+}
+
+func (h *HNSW) Size() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes)
+}
+
+func (h *HNSW) randomLayer() int {
+	// Standard HNSW level assignment: P(layer >= l) decays geometrically.
+	levelMult := 1.0 / math.Log(float64(h.M))
+	layer := int(-math.Log(h.rnd.Float64()+1e-12) * levelMult)
+	if layer > 32 {
+		layer = 32
+	}
+	return layer
+}
+
+// Add inserts vec under id, connecting it into the graph via a greedy
+// descent from the current entry point followed by a bounded
+// neighbor-search at each layer from its assigned level down to 0.
+func (h *HNSW) Add(id string, vec []float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	layer := h.randomLayer()
+	node := &hnswNode{ID: id, Vec: vec, Layer: layer, Neighbors: map[int][]string{}}
+	h.nodes[id] = node
+
+	if h.entryPoint == "" {
+		h.entryPoint = id
+		h.maxLayer = layer
+		return nil
+	}
+
+	entry := h.entryPoint
+	// Descend from the current top layer to layer+1 with a pure greedy walk.
+	for l := h.maxLayer; l > layer; l-- {
+		entry = h.greedyClosest(entry, vec, l)
+	}
+	// From layer down to 0, do a bounded beam search and connect up to M
+	// closest candidates at each level.
+	for l := min(layer, h.maxLayer); l >= 0; l-- {
+		candidates := h.searchLayer(vec, entry, h.EfConstruction, l)
+		neighbors := selectClosest(candidates, h.M)
+		for _, c := range neighbors {
+			h.connect(node, h.nodes[c.ID], l)
+		}
+		if len(candidates) > 0 {
+			entry = candidates[0].ID
+		}
+	}
+
+	if layer > h.maxLayer {
+		h.maxLayer = layer
+		h.entryPoint = id
+	}
+	return nil
+}
+
+func (h *HNSW) connect(a, b *hnswNode, layer int) {
+	if a == nil || b == nil || a.ID == b.ID {
+		return
+	}
+	a.Neighbors[layer] = appendBounded(a.Neighbors[layer], b.ID, h.M)
+	b.Neighbors[layer] = appendBounded(b.Neighbors[layer], a.ID, h.M)
+}
+
+func appendBounded(list []string, id string, max int) []string {
+	for _, existing := range list {
+		if existing == id {
+			return list
+		}
+	}
+	list = append(list, id)
+	if len(list) > max {
+		list = list[len(list)-max:]
+	}
+	return list
+}
+
+// greedyClosest walks from entry towards vec at a single layer, returning
+// the closest node found (used only above the insert/query's own layer).
+func (h *HNSW) greedyClosest(entry string, vec []float64, layer int) string {
+	best := entry
+	bestScore := CosineSimilarity(vec, h.nodes[entry].Vec)
+	improved := true
+	for improved {
+		improved = false
+		for _, nb := range h.nodes[best].Neighbors[layer] {
+			if n, ok := h.nodes[nb]; ok {
+				score := CosineSimilarity(vec, n.Vec)
+				if score > bestScore {
+					bestScore = score
+					best = nb
+					improved = true
+				}
+			}
+		}
+	}
+	return best
+}
+
+// searchLayer runs a beam search of width ef at the given layer, returning
+// candidates sorted by descending similarity.
+func (h *HNSW) searchLayer(vec []float64, entry string, ef, layer int) []SearchResult {
+	visited := map[string]bool{entry: true}
+	candidates := []SearchResult{{ID: entry, Score: CosineSimilarity(vec, h.nodes[entry].Vec)}}
+	best := append([]SearchResult{}, candidates...)
+
+	for len(candidates) > 0 {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+		top := candidates[0]
+		candidates = candidates[1:]
+
+		worstBest := math.Inf(-1)
+		if len(best) >= ef {
+			sort.Slice(best, func(i, j int) bool { return best[i].Score > best[j].Score })
+			worstBest = best[min(ef, len(best))-1].Score
+			if top.Score < worstBest {
+				break
+			}
+		}
+
+		for _, nbID := range h.nodes[top.ID].Neighbors[layer] {
+			if visited[nbID] {
+				continue
+			}
+			visited[nbID] = true
+			n, ok := h.nodes[nbID]
+			if !ok {
+				continue
+			}
+			score := CosineSimilarity(vec, n.Vec)
+			candidates = append(candidates, SearchResult{ID: nbID, Score: score})
+			best = append(best, SearchResult{ID: nbID, Score: score})
+		}
+	}
+
+	sort.Slice(best, func(i, j int) bool { return best[i].Score > best[j].Score })
+	if len(best) > ef {
+		best = best[:ef]
+	}
+	return best
+}
+
+func selectClosest(candidates []SearchResult, m int) []SearchResult {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > m {
+		candidates = candidates[:m]
+	}
+	return candidates
+}
+
+// Search returns the top-k nearest neighbors to query by cosine similarity.
+func (h *HNSW) Search(query []float64, k int) ([]SearchResult, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entryPoint == "" {
+		return nil, nil
+	}
+	entry := h.entryPoint
+	for l := h.maxLayer; l > 0; l-- {
+		entry = h.greedyClosest(entry, query, l)
+	}
+	candidates := h.searchLayer(query, entry, max(h.EfSearch, k), 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return candidates, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ── Persistence ──────────────────────────────────────────────────────────
+
+// hnswSnapshot is the JSON-serializable form of one node, stored as a row
+// in the vector_index table (nodes, layer, neighbor lists as blobs).
+type hnswSnapshot struct {
+	ID        string           `json:"id"`
+	Vec       []float64        `json:"vec"`
+	Layer     int              `json:"layer"`
+	Neighbors map[int][]string `json:"neighbors"`
+}
+
+// MarshalNode serializes a single node for storage in vector_index.
+func (h *HNSW) MarshalNode(id string) ([]byte, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n, ok := h.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	b, err := json.Marshal(hnswSnapshot{ID: n.ID, Vec: n.Vec, Layer: n.Layer, Neighbors: n.Neighbors})
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// LoadNode restores a single previously-marshaled node, preserving its
+// original layer and neighbor lists rather than reassigning a random layer.
+func (h *HNSW) LoadNode(data []byte) error {
+	var snap hnswSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodes[snap.ID] = &hnswNode{ID: snap.ID, Vec: snap.Vec, Layer: snap.Layer, Neighbors: snap.Neighbors}
+	if snap.Layer > h.maxLayer || h.entryPoint == "" {
+		h.maxLayer = snap.Layer
+		h.entryPoint = snap.ID
+	}
+	return nil
+}
+
+// EntryPointID and MaxLayer are exposed so the loader can double check the
+// restored graph's metadata against what the vector_meta table recorded.
+func (h *HNSW) EntryPointID() string { return h.entryPoint }
+func (h *HNSW) MaxLayerLevel() int   { return h.maxLayer }
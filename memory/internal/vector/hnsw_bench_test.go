@@ -0,0 +1,69 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// synthetic50kCorpus returns 50k pseudo-random vectors of length dim, each
+// component uniform in [-1, 1), for BenchmarkVSearch50k.
+// The seed is fixed so -count>1 runs compare apples to apples.
+func synthetic50kCorpus(dim int) [][]float64 {
+	rnd := rand.New(rand.NewSource(42))
+	corpus := make([][]float64, 50000)
+	for i := range corpus {
+		vec := make([]float64, dim)
+		for d := range vec {
+			vec[d] = rnd.Float64()*2 - 1
+		}
+		corpus[i] = vec
+	}
+	return corpus
+}
+
+// BenchmarkVSearch50k measures vsearch's two costs at a realistic corpus
+// size: the on-disk footprint of the persisted vector_index (via
+// MarshalNode, the same serialization CmdReindex/indexEmbedding write) and
+// Search latency once the graph is built. Run with `go test -bench
+// VSearch50k -benchtime 20x ./internal/vector` — it's excluded from a plain
+// `go test ./...` run since building a 50k-node graph is too slow for the
+// normal build gate.
+func BenchmarkVSearch50k(b *testing.B) {
+	const dim = 64
+	corpus := synthetic50kCorpus(dim)
+
+	idx := NewHNSW(16, 200, 64)
+	for i, vec := range corpus {
+		if err := idx.Add(fmt.Sprintf("fact:%d", i), vec); err != nil {
+			b.Fatalf("Add: %v", err)
+		}
+	}
+
+	var totalBytes int
+	for i := range corpus {
+		node, ok := idx.MarshalNode(fmt.Sprintf("fact:%d", i))
+		if !ok {
+			b.Fatalf("MarshalNode: missing node %d", i)
+		}
+		totalBytes += len(node)
+	}
+	b.ReportMetric(float64(totalBytes)/float64(len(corpus)), "bytes/node")
+
+	rnd := rand.New(rand.NewSource(7))
+	queries := make([][]float64, 256)
+	for i := range queries {
+		q := make([]float64, dim)
+		for d := range q {
+			q[d] = rnd.Float64()*2 - 1
+		}
+		queries[i] = q
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := idx.Search(queries[i%len(queries)], 10); err != nil {
+			b.Fatalf("Search: %v", err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package vector
+
+import "github.com/golang/snappy"
+
+// TextCompressionThreshold is the minimum byte length of a summary/
+// patterns/fact value before CompressText bothers Snappy-compressing it;
+// short rows aren't worth the column-flag overhead.
+const TextCompressionThreshold = 256
+
+// CompressText Snappy-compresses text when it's at least
+// TextCompressionThreshold bytes long. compressed is false (and data is
+// nil) when text was left alone, so callers know to keep using the plain
+// column instead of the compressed one.
+func CompressText(text string) (data []byte, compressed bool) {
+	if len(text) < TextCompressionThreshold {
+		return nil, false
+	}
+	return snappy.Encode(nil, []byte(text)), true
+}
+
+// DecompressText is the inverse of CompressText.
+func DecompressText(data []byte) (string, error) {
+	out, err := snappy.Decode(nil, data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,64 @@
+// Package vector holds embedding math and the on-disk ANN index used by
+// vsearch: hex<->float64 blob conversion, cosine similarity, int8
+// quantization and Snappy text compression for compact storage (codec.go,
+// text_codec.go), and (in hnsw.go) an HNSW index for sublinear
+// nearest-neighbor search.
+package vector
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// HexBlobToFloat64 decodes a hex-encoded embedding column value back to
+// float64, transparently handling both blob formats it may hold: the
+// legacy bare sequence of big-endian float64s sqlite3's hex() produces, and
+// the quantized form written by QuantizeVector/Quantize (see codec.go).
+// Callers that don't need float64 specifically (CmdVSearch's hot loop)
+// should prefer DecodeEmbeddingHex + CosineSimilarityQuantized instead,
+// which skip reconstructing the float64 vector entirely.
+func HexBlobToFloat64(hexStr string) ([]float64, error) {
+	raw, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex embedding: %w", err)
+	}
+	if q, ok := decodeQuantized(raw); ok {
+		return q.Floats(), nil
+	}
+	if len(raw)%8 != 0 {
+		return nil, fmt.Errorf("embedding blob length %d is not a multiple of 8", len(raw))
+	}
+	return bytesToFloats(raw), nil
+}
+
+// Float64ToBytes encodes vec as the legacy bare big-endian float64 blob.
+// New writes should use QuantizeVector instead; this remains for decoding
+// symmetry and for `memorydb migrate-vectors` to recognize what it's
+// replacing.
+func Float64ToBytes(vec []float64) []byte {
+	out := make([]byte, len(vec)*8)
+	for i, f := range vec {
+		binary.BigEndian.PutUint64(out[i*8:i*8+8], math.Float64bits(f))
+	}
+	return out
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0 if
+// either vector has zero magnitude or they differ in length.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, magA, magB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		magA += a[i] * a[i]
+		magB += b[i] * b[i]
+	}
+	if magA == 0 || magB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(magA) * math.Sqrt(magB))
+}